@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/haapjari/glass/pkg/analysis"
+	"github.com/haapjari/glass/pkg/config"
+)
+
+// Usage: glass analyze [-o dir] [-libraries=false] [-dataset FILE] [thresholds] <path>
+//
+// Exits with 1, if any of the thresholds fails, so the command can be used as a CI step.
+func runAnalyze(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+
+	output := flags.String("o", "glass-report", "directory to write "+analysis.JsonFileName+", "+analysis.MarkdownFileName+" and "+analysis.JunitFileName+" to")
+	libraries := flags.Bool("libraries", true, "download the libraries and measure their size")
+
+	var o analysis.Options
+
+	flags.StringVar(&o.Dataset, "dataset", "", "export bundle of a dataset, e.g. of glass export, to score the factors of the Quality Measure against")
+
+	flags.Float64Var(&o.Thresholds.MaxLibraryRatio, "max-library-ratio", 0, "fail, if the library to original code ratio is above")
+	flags.IntVar(&o.Thresholds.MaxDependencies, "max-dependencies", 0, "fail, if there are more libraries than")
+	flags.IntVar(&o.Thresholds.MinCommits, "min-commits", 0, "fail, if there are less commits than")
+	flags.IntVar(&o.Thresholds.MaxReleaseAgeDays, "max-release-age-days", 0, "fail, if the latest release is older than")
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: glass analyze [-o dir] [-libraries=false] [-dataset FILE] [-max-library-ratio N] [-max-dependencies N] [-min-commits N] [-max-release-age-days N] <path>")
+		os.Exit(2)
+	}
+
+	o.Libraries = *libraries
+
+	report, err := analysis.Analyze(flags.Arg(0), o)
+	if err != nil {
+		exit(err, 1)
+	}
+
+	if err := report.WriteReports(*output); err != nil {
+		exit(err, 1)
+	}
+
+	report.WriteMarkdown(os.Stdout)
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
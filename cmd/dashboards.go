@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/metrics/grafana"
+)
+
+// Usage: glass dashboards [-o grafana] [-prometheus http://prometheus:9090]
+func runDashboards(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("dashboards", flag.ExitOnError)
+
+	output := flags.String("o", "grafana", "directory to write the dashboards and the provisioning files to")
+	prometheus := flags.String("prometheus", "http://prometheus:9090", "URL of Prometheus, which Grafana queries")
+
+	flags.Parse(args)
+
+	paths, err := grafana.Write(*output, *prometheus)
+	if err != nil {
+		exit(err, 1)
+	}
+
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+}
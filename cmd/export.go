@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/export"
+	"gorm.io/gorm"
+)
+
+// Usage: glass export [-format parquet|jsonl|csv] [-o bundle.tar.gz|-]
+func runExport(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+
+	format := flags.String("format", string(export.FormatParquet), "format of the tables: parquet, jsonl or csv")
+	output := flags.String("o", "", "file to write the bundle to, - for stdout. Defaults to the name of the bundle")
+
+	flags.Parse(args)
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		exit(err, 2)
+	}
+
+	if err := writeBundle(openDatabase(c), f, *output); err != nil {
+		exit(err, 1)
+	}
+}
+
+// Writes the bundle to the file, or to stdout with "-". Empty name defaults to the name of the bundle.
+func writeBundle(db *gorm.DB, format export.Format, name string) error {
+	bundle, err := export.NewBundle(db, format)
+	if err != nil {
+		return err
+	}
+
+	defer bundle.Remove()
+
+	if name == "" {
+		name = bundle.FileName()
+	}
+
+	if name == "-" {
+		return bundle.Archive(os.Stdout)
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if err := bundle.Archive(file); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, name)
+
+	return file.Close()
+}
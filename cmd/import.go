@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/importer"
+)
+
+// Usage: glass import [-dry-run] [-on-conflict skip|overwrite] <bundle.tar.gz>
+func runImport(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+
+	dryRun := flags.Bool("dry-run", false, "report inserts, updates and conflicts without writing to the database")
+	onConflict := flags.String("on-conflict", importer.OnConflictSkip, "resolution of conflicting values: skip or overwrite")
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: glass import [-dry-run] [-on-conflict skip|overwrite] <bundle.tar.gz>")
+		os.Exit(2)
+	}
+
+	options, err := importer.ParseOptions(fmt.Sprint(*dryRun), *onConflict)
+	if err != nil {
+		exit(err, 2)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		exit(err, 1)
+	}
+
+	defer file.Close()
+
+	report, err := importer.Import(openDatabase(c), file, options)
+	if err != nil {
+		exit(err, 1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
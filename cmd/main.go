@@ -1,9 +1,130 @@
 package main
 
 import (
-	"github.com/haapjari/glass/pkg/router"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/logging"
+	"gorm.io/gorm"
 )
 
+// Usage: glass [-config .env] [-set KEY=VALUE]... <command> [flags]. Without a command, the HTTP API is served.
+
+type command struct {
+	Run         func(c *config.Config, args []string)
+	Description string
+}
+
+var commands = map[string]command{
+	"serve":      {runServe, "serve the HTTP API"},
+	"analyze":    {runAnalyze, "analyze a local checkout, and write JSON, Markdown and JUnit reports"},
+	"fetch":      {runFetch, "search repositories, and collect their metadata and codebase sizes"},
+	"enrich":     {runEnrich, "collect the metadata of the repositories in the database"},
+	"measure":    {runMeasure, "calculate the codebase sizes of the repositories in the database"},
+	"quality":    {runQuality, "calculate the Quality Measure of the repositories in the database"},
+	"export":     {runExport, "write a bundle of the dataset"},
+	"import":     {runImport, "merge a bundle to the database"},
+	"migrate":    {runMigrate, "create and update the database tables"},
+	"dashboards": {runDashboards, "write the Grafana dashboards and their provisioning files"},
+}
+
+// Repeatable "-set KEY=VALUE" -flag.
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
+}
+
 func main() {
-	router.SetupRouter()
+	flags := flag.NewFlagSet("glass", flag.ExitOnError)
+	flags.Usage = usage
+
+	file := flags.String("config", config.DefaultFile, "configuration file")
+
+	var set overrides
+
+	flags.Var(&set, "set", "override a configuration value, e.g. -set POSTGRES_HOST=localhost")
+
+	flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+
+	if len(args) > 0 && args[0] == "help" {
+		usage()
+		return
+	}
+
+	c, err := config.Load(*file, set)
+	if err != nil {
+		exit(err, 2)
+	}
+
+	logging.Setup(c.Log)
+
+	if len(args) == 0 {
+		runServe(c, nil)
+		return
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd.Run(c, args[1:])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: glass [-config .env] [-set KEY=VALUE]... <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	width := 0
+
+	for name := range commands {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", width, name, commands[name].Description)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "configuration keys: "+strings.Join(config.Keys, ", "))
+	fmt.Fprintln(os.Stderr, "stage settings: "+config.StageKey("<stage>", "{WORKERS,TIMEOUT,BATCH_SIZE,DISK_BUDGET}")+", stages: "+strings.Join(config.Stages, ", "))
+}
+
+// Validates the configuration of the database, connects to it, and migrates the tables.
+func openDatabase(c *config.Config) *gorm.DB {
+	if err := c.Validate(config.RequireDatabase); err != nil {
+		exit(err, 2)
+	}
+
+	db, err := database.SetupDatabase(c.Database)
+	if err != nil {
+		exit(err, 1)
+	}
+
+	return db
+}
+
+// Prints the error, and exits with the code.
+func exit(err error, code int) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(code)
 }
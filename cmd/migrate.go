@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+)
+
+// Usage: glass migrate
+func runMigrate(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Parse(args)
+
+	if err := c.Validate(config.RequireDatabase); err != nil {
+		exit(err, 2)
+	}
+
+	db, err := database.Connect(c.Database)
+	if err != nil {
+		exit(err, 1)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		exit(err, 1)
+	}
+}
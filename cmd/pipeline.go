@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/plugins"
+)
+
+// Stages of the data collection, the same the fetch endpoint of the API runs.
+
+// Usage: glass fetch [-plugin go] [-only] [-sources FILE] [-seeds FILE [-tag TAG]] -count N
+func runFetch(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+
+	name := pluginFlag(flags)
+	count := flags.Int("count", 0, "amount of new repositories to discover from each source")
+	only := flags.Bool("only", false, "only search the repositories, without enriching and measuring them")
+	sourcesFile := flags.String("sources", "", "JSON file of the discovery sources, the sources of the job endpoint of the API")
+	seedsFile := flags.String("seeds", "", "seed list of repositories, one on a line, e.g. github.com/owner/name")
+	tag := flags.String("tag", "", "tag of the repositories of the seed list, defaults to the name of the file")
+
+	flags.Parse(args)
+
+	if *count < 1 {
+		fmt.Fprintln(os.Stderr, "usage: glass fetch [-plugin go] [-only] [-sources FILE] [-seeds FILE [-tag TAG]] -count N")
+		os.Exit(2)
+	}
+
+	var sources []models.Source
+
+	if *sourcesFile != "" {
+		s, err := readSources(*sourcesFile)
+		if err != nil {
+			exit(err, 2)
+		}
+
+		sources = append(sources, s...)
+	}
+
+	if *seedsFile != "" {
+		s, err := readSeeds(*seedsFile, *tag)
+		if err != nil {
+			exit(err, 2)
+		}
+
+		sources = append(sources, s)
+	}
+
+	plugin := newPlugin(c, *name)
+
+	if *only {
+		if err := plugin.Fetch(*count, sources); err != nil {
+			exit(err, 1)
+		}
+		return
+	}
+
+	if err := plugins.Run(plugin, *count, sources); err != nil {
+		exit(err, 1)
+	}
+}
+
+// Reads the JSON array of the sources, e.g. [{"type": "github", "query": "language:go stars:>100", "tag": "popular"}].
+func readSources(file string) ([]models.Source, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []models.Source
+
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("reading sources %s: %w", file, err)
+	}
+
+	for i, s := range sources {
+		if err := validateSource(s); err != nil {
+			return nil, fmt.Errorf("reading sources %s: source %d: %w", file, i, err)
+		}
+	}
+
+	return sources, nil
+}
+
+// Reads the seed list of repositories, one on a line. Empty lines and lines starting with "#"
+// are skipped.
+func readSeeds(file string, tag string) (models.Source, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return models.Source{}, err
+	}
+
+	if tag == "" {
+		tag = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	source := models.Source{Type: models.SourceTypeSeeds, Tag: tag}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			// "owner/name" is a repository of GitHub. Hosts have a dot, owners don't.
+			if owner, _, ok := strings.Cut(strings.Trim(line, "/"), "/"); ok && !strings.Contains(owner, ".") && !strings.Contains(line, ":") {
+				line = "github.com/" + strings.Trim(line, "/")
+			}
+
+			source.Repositories = append(source.Repositories, line)
+		}
+	}
+
+	if err := validateSource(source); err != nil {
+		return models.Source{}, fmt.Errorf("reading seeds %s: %w", file, err)
+	}
+
+	return source, nil
+}
+
+// Validates the source with the rules of the sources of the job endpoint of the API.
+func validateSource(s models.Source) error {
+	var errs validator.ValidationErrors
+
+	if err := binding.Validator.ValidateStruct(&s); !errors.As(err, &errs) {
+		return err
+	}
+
+	fields := apierror.Fields(errs)
+	messages := make([]string, len(fields))
+
+	for i, f := range fields {
+		messages[i] = f.Field + " " + f.Message
+
+		// Names the seed, which isn't a repository.
+		if f.Code == "seed" {
+			messages[i] = fmt.Sprintf("%s %q %s", f.Field, errs[i].Value(), f.Message)
+		}
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// Usage: glass enrich [-plugin go]
+func runEnrich(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("enrich", flag.ExitOnError)
+
+	name := pluginFlag(flags)
+
+	flags.Parse(args)
+
+	if err := newPlugin(c, *name).Enrich(); err != nil {
+		exit(err, 1)
+	}
+}
+
+// Usage: glass measure [-plugin go]
+func runMeasure(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("measure", flag.ExitOnError)
+
+	name := pluginFlag(flags)
+
+	flags.Parse(args)
+
+	if err := newPlugin(c, *name).Measure(); err != nil {
+		exit(err, 1)
+	}
+}
+
+func pluginFlag(flags *flag.FlagSet) *string {
+	return flags.String("plugin", "go", "plugin, which collects the repositories: "+strings.Join(plugins.Names, ", "))
+}
+
+// Validates the configuration of the plugin before connecting to the database.
+func newPlugin(c *config.Config, name string) plugins.Plugin {
+	requirements, err := plugins.Requirements(name)
+	if err != nil {
+		exit(err, 2)
+	}
+
+	if err := c.Validate(requirements...); err != nil {
+		exit(err, 2)
+	}
+
+	plugin, err := plugins.New(name, openDatabase(c), c, 0)
+	if err != nil {
+		exit(err, 2)
+	}
+
+	return plugin
+}
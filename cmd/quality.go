@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/quality"
+)
+
+// Usage: glass quality [-format json|csv]
+func runQuality(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("quality", flag.ExitOnError)
+
+	format := flags.String("format", "json", "output format: json or csv")
+
+	flags.Parse(args)
+
+	if *format != "json" && *format != "csv" {
+		exit(fmt.Errorf("unsupported format: %s", *format), 2)
+	}
+
+	var repositories []models.Repository
+
+	if err := openDatabase(c).Order("id").Find(&repositories).Error; err != nil {
+		exit(err, 1)
+	}
+
+	scores := quality.Measure(repositories)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(scores)
+
+		return
+	}
+
+	w := csv.NewWriter(os.Stdout)
+
+	header := []string{"repository_name", "quality_measure", "quality_measure_version"}
+	for _, f := range quality.Factors {
+		header = append(header, f.Name)
+	}
+
+	w.Write(header)
+
+	for _, s := range scores {
+		row := []string{s.RepositoryName, formatScore(s.QualityMeasure), s.QualityMeasureVersion}
+
+		for _, f := range quality.Factors {
+			if v, ok := s.Factors[f.Name]; ok {
+				row = append(row, formatScore(v))
+			} else {
+				row = append(row, "")
+			}
+		}
+
+		w.Write(row)
+	}
+
+	w.Flush()
+}
+
+func formatScore(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/router"
+)
+
+// Usage: glass serve
+func runServe(c *config.Config, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Parse(args)
+
+	if err := c.Validate(config.RequireDatabase); err != nil {
+		exit(err, 2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := router.SetupRouter(ctx, c); err != nil {
+		exit(err, 1)
+	}
+}
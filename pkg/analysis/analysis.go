@@ -0,0 +1,289 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/plugins/goplg"
+	"github.com/haapjari/glass/pkg/quality"
+	"github.com/haapjari/glass/pkg/version"
+)
+
+// Analyzes a single local checkout of a Go repository, e.g. in a CI pipeline. The codebase and
+// library sizes are measured the same way as in the Go plugin. Factors of the Quality Measure,
+// which need GitHub (stars, issues), are not available, and the ones read from git are missing
+// from shallow clones. Factors are scored by their rank within a dataset, so they are scored
+// only against the repositories of a dataset export.
+
+type Options struct {
+	// Download the libraries and measure their size.
+	Libraries bool
+
+	// Export bundle of a dataset, e.g. written by "glass export", which the factors are scored
+	// against. Empty doesn't score the factors.
+	Dataset string
+
+	Thresholds Thresholds
+}
+
+// Thresholds fail the analysis, when exceeded. Zero values are disabled.
+type Thresholds struct {
+	MaxLibraryRatio   float64
+	MaxDependencies   int
+	MinCommits        int
+	MaxReleaseAgeDays int
+}
+
+type Report struct {
+	Path                  string    `json:"path"`
+	Module                string    `json:"module"`
+	GlassVersion          string    `json:"glass_version"`
+	QualityMeasureVersion string    `json:"quality_measure_version"`
+	AnalyzedAt            time.Time `json:"analyzed_at"`
+
+	OriginalCodebaseSize int             `json:"original_codebase_size"`
+	LibraryCodebaseSize  int             `json:"library_codebase_size"`
+	LibraryRatio         float64         `json:"library_ratio"`
+	Libraries            []goplg.Library `json:"libraries"`
+
+	// Read from git. Empty, if not available.
+	CommitCount   int    `json:"commit_count,omitempty"`
+	CreationDate  string `json:"creation_date,omitempty"`
+	LatestRelease string `json:"latest_release,omitempty"`
+
+	// Scores of the factors, which have a value, against the dataset, see quality.Measure.
+	QualityMeasure float64            `json:"quality_measure,omitempty"`
+	Factors        map[string]float64 `json:"factors,omitempty"`
+
+	Notes  []string `json:"notes,omitempty"`
+	Checks []Check  `json:"checks"`
+	Passed bool     `json:"passed"`
+}
+
+// Value of a check, which the analysis has no value of.
+const notAvailable = "not available"
+
+type Check struct {
+	Name      string `json:"name"`
+	Threshold string `json:"threshold"`
+	Value     string `json:"value"`
+	Passed    bool   `json:"passed"`
+}
+
+// Analyze measures the working tree in the path, and checks the thresholds.
+func Analyze(path string, o Options) (*Report, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	modFile, err := os.ReadFile(filepath.Join(path, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("not a Go module: %w", err)
+	}
+
+	var dataset []models.Repository
+
+	if o.Dataset != "" {
+		if dataset, err = readDataset(o.Dataset); err != nil {
+			return nil, fmt.Errorf("reading dataset %s: %w", o.Dataset, err)
+		}
+	}
+
+	r := &Report{
+		Path:                  path,
+		Module:                goplg.ModulePath(string(modFile)),
+		GlassVersion:          version.Version,
+		QualityMeasureVersion: version.QualityMeasureVersion,
+		AnalyzedAt:            time.Now().UTC(),
+	}
+
+	if r.OriginalCodebaseSize, err = goplg.CodeLines(path); err != nil {
+		return nil, err
+	}
+
+	if r.Libraries, err = goplg.LocalLibraries(path); err != nil {
+		return nil, err
+	}
+
+	if o.Libraries {
+		if err := goplg.DownloadLibraries(path, r.Libraries); err != nil {
+			return nil, err
+		}
+
+		for _, l := range r.Libraries {
+			r.LibraryCodebaseSize += l.CodeLines
+
+			if l.Error != "" {
+				r.Notes = append(r.Notes, fmt.Sprintf("library %s@%s was not measured: %s", l.Module, l.Version, strings.Join(strings.Fields(l.Error), " ")))
+			}
+		}
+
+		if r.OriginalCodebaseSize > 0 {
+			r.LibraryRatio = math.Round(float64(r.LibraryCodebaseSize)/float64(r.OriginalCodebaseSize)*100) / 100
+		}
+	} else {
+		r.Notes = append(r.Notes, "libraries were not downloaded, library codebase size is not measured")
+	}
+
+	r.readGit()
+
+	if o.Dataset != "" {
+		r.score(dataset)
+	} else {
+		r.Notes = append(r.Notes, "no dataset, the factors of the Quality Measure are not scored")
+	}
+
+	r.check(o)
+
+	return r, nil
+}
+
+// Reads the commit count, the creation date and the latest release from the git history.
+func (r *Report) readGit() {
+	if _, err := git(r.Path, "rev-parse", "--git-dir"); err != nil {
+		r.Notes = append(r.Notes, "not a git repository, commit count, creation date and latest release are not available")
+		return
+	}
+
+	if tag, err := git(r.Path, "for-each-ref", "--sort=-creatordate", "--count=1", "--format=%(creatordate:iso-strict)", "refs/tags"); err == nil {
+		r.LatestRelease = tag
+	}
+
+	if shallow, _ := git(r.Path, "rev-parse", "--is-shallow-repository"); shallow == "true" {
+		r.Notes = append(r.Notes, "shallow clone, commit count and creation date are not available (fetch the full history, e.g. fetch-depth: 0)")
+		return
+	}
+
+	if count, err := git(r.Path, "rev-list", "--count", "HEAD"); err == nil {
+		r.CommitCount, _ = strconv.Atoi(count)
+	}
+
+	// Root commits are listed newest first, the last one is the oldest.
+	if roots, err := git(r.Path, "log", "--max-parents=0", "--format=%aI", "HEAD"); err == nil && roots != "" {
+		lines := strings.Split(roots, "\n")
+		r.CreationDate = lines[len(lines)-1]
+	}
+}
+
+// Scores the factors, which git has values of, against the repositories of the dataset.
+func (r *Report) score(dataset []models.Repository) {
+	repository := models.Repository{RepositoryName: r.Module, CreationDate: r.CreationDate, LatestRelease: r.LatestRelease}
+
+	if r.CommitCount > 0 {
+		repository.CommitCount = strconv.Itoa(r.CommitCount)
+	}
+
+	scores := quality.Measure(append(dataset[:len(dataset):len(dataset)], repository))
+	score := scores[len(scores)-1]
+
+	r.QualityMeasure = score.QualityMeasure
+	r.Factors = score.Factors
+
+	var missing []string
+
+	for _, f := range quality.Factors {
+		if _, ok := score.Factors[f.Name]; !ok {
+			missing = append(missing, f.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		r.Notes = append(r.Notes, fmt.Sprintf("Quality Measure is the average of the available factors, %s are not available", strings.Join(missing, ", ")))
+	}
+}
+
+// Reads the repositories of an export bundle.
+func readDataset(file string) ([]models.Repository, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	bundle, err := export.OpenBundle(f)
+	if err != nil {
+		return nil, err
+	}
+
+	defer bundle.Remove()
+
+	var repositories []models.Repository
+
+	err = export.ReadTable(bundle, "repositories", func(row *models.Repository) error {
+		repositories = append(repositories, *row)
+		return nil
+	})
+
+	return repositories, err
+}
+
+func (r *Report) check(o Options) {
+	t := o.Thresholds
+
+	// Thresholds, which can't be checked, e.g. of a shallow clone, fail, so a CI step doesn't pass unchecked.
+	if t.MaxLibraryRatio > 0 {
+		c := Check{Name: "library_ratio", Threshold: "<= " + strconv.FormatFloat(t.MaxLibraryRatio, 'f', -1, 64), Value: notAvailable}
+
+		if o.Libraries {
+			c.Value = strconv.FormatFloat(r.LibraryRatio, 'f', -1, 64)
+			c.Passed = r.LibraryRatio <= t.MaxLibraryRatio
+		}
+
+		r.Checks = append(r.Checks, c)
+	}
+
+	if t.MaxDependencies > 0 {
+		r.Checks = append(r.Checks, Check{
+			Name:      "dependencies",
+			Threshold: "<= " + strconv.Itoa(t.MaxDependencies),
+			Value:     strconv.Itoa(len(r.Libraries)),
+			Passed:    len(r.Libraries) <= t.MaxDependencies,
+		})
+	}
+
+	if t.MinCommits > 0 {
+		c := Check{Name: "commit_count", Threshold: ">= " + strconv.Itoa(t.MinCommits), Value: notAvailable}
+
+		if r.CommitCount > 0 {
+			c.Value = strconv.Itoa(r.CommitCount)
+			c.Passed = r.CommitCount >= t.MinCommits
+		}
+
+		r.Checks = append(r.Checks, c)
+	}
+
+	if t.MaxReleaseAgeDays > 0 {
+		c := Check{Name: "release_age_days", Threshold: "<= " + strconv.Itoa(t.MaxReleaseAgeDays), Value: "no release"}
+
+		if released, err := time.Parse(time.RFC3339, r.LatestRelease); err == nil {
+			days := int(r.AnalyzedAt.Sub(released).Hours() / 24)
+
+			c.Value = strconv.Itoa(days)
+			c.Passed = days <= t.MaxReleaseAgeDays
+		}
+
+		r.Checks = append(r.Checks, c)
+	}
+
+	r.Passed = true
+
+	for _, c := range r.Checks {
+		r.Passed = r.Passed && c.Passed
+	}
+}
+
+func git(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+
+	return strings.TrimSpace(string(out)), err
+}
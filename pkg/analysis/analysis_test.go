@@ -0,0 +1,189 @@
+package analysis
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/quality"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var dataset = []models.Repository{
+	{RepositoryName: "github.com/o/a", CommitCount: "10", CreationDate: "2015-01-01T00:00:00Z", LatestRelease: "2020-01-01T00:00:00Z", StargazerCount: "100"},
+	{RepositoryName: "github.com/o/b", CommitCount: "30", CreationDate: "2018-01-01T00:00:00Z", LatestRelease: "2022-01-01T00:00:00Z"},
+	{RepositoryName: "github.com/o/c", CreationDate: "2020-01-01T00:00:00Z"},
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  Report
+		factors map[string]float64
+		measure float64
+	}{
+		{
+			name:   "git values",
+			report: Report{CommitCount: 20, CreationDate: "2016-01-01T00:00:00+02:00", LatestRelease: "2023-01-01T00:00:00Z"},
+			factors: map[string]float64{
+				quality.FactorActivity:      2.5,
+				quality.FactorMaturity:      3.33,
+				quality.FactorLatestRelease: 5,
+			},
+			measure: 3.61,
+		},
+		{
+			name:    "shallow clone without a release",
+			report:  Report{},
+			factors: map[string]float64{},
+		},
+		{
+			name:    "shallow clone",
+			report:  Report{LatestRelease: "2021-01-01T00:00:00Z"},
+			factors: map[string]float64{quality.FactorLatestRelease: 2.5},
+			measure: 2.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.report
+			r.score(dataset)
+
+			if len(r.Factors) != len(tt.factors) {
+				t.Errorf("factors = %v, want %v", r.Factors, tt.factors)
+			}
+
+			for name, want := range tt.factors {
+				if got, ok := r.Factors[name]; !ok || got != want {
+					t.Errorf("factor %s = %v, want %v", name, got, want)
+				}
+			}
+
+			if r.QualityMeasure != tt.measure {
+				t.Errorf("quality measure = %v, want %v", r.QualityMeasure, tt.measure)
+			}
+
+			if len(r.Notes) != 1 || !strings.Contains(r.Notes[0], quality.FactorPopularity) {
+				t.Errorf("notes = %v, want the missing factors", r.Notes)
+			}
+		})
+	}
+}
+
+func TestReadDataset(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Repository{}, &models.Commit{}, &models.Snapshot{}); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Create(&dataset)
+
+	bundle, err := export.NewBundle(db, export.FormatJsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer bundle.Remove()
+
+	file := filepath.Join(t.TempDir(), bundle.FileName())
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bundle.Archive(f); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	repositories, err := readDataset(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repositories) != len(dataset) || repositories[1].CommitCount != "30" {
+		t.Errorf("repositories = %+v, want %+v", repositories, dataset)
+	}
+}
+
+// Returns a shallow clone of a Go module of two commits.
+func newShallowClone(t *testing.T) string {
+	t.Helper()
+
+	origin := t.TempDir()
+
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	run(origin, "init", "-q")
+
+	files := []struct{ name, content string }{
+		{"go.mod", "module example.com/m\n\ngo 1.21\n"},
+		{"main.go", "package main\n\nfunc main() {}\n"},
+	}
+
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(origin, f.name), []byte(f.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		run(origin, "add", f.name)
+		run(origin, "commit", "-q", "-m", f.name)
+	}
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	run(origin, "clone", "-q", "--depth", "1", "file://"+origin, clone)
+
+	return clone
+}
+
+func TestAnalyzeUncheckedThresholds(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	path := newShallowClone(t)
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		check      string
+	}{
+		{"commits of a shallow clone", Thresholds{MinCommits: 1}, "commit_count"},
+		{"library ratio without the libraries", Thresholds{MaxLibraryRatio: 10}, "library_ratio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Analyze(path, Options{Thresholds: tt.thresholds})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Passed || len(r.Checks) != 1 {
+				t.Fatalf("passed = %v, checks = %+v, want a failing check", r.Passed, r.Checks)
+			}
+
+			if c := r.Checks[0]; c.Name != tt.check || c.Value != notAvailable || c.Passed {
+				t.Errorf("check = %+v, want %s %s", c, tt.check, notAvailable)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/haapjari/glass/pkg/quality"
+)
+
+// File names of the reports written by WriteReports.
+const (
+	JsonFileName     = "report.json"
+	MarkdownFileName = "report.md"
+	JunitFileName    = "junit.xml"
+)
+
+// WriteReports writes the JSON, Markdown and JUnit reports to the directory.
+func (r *Report) WriteReports(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	writers := map[string]func(w io.Writer) error{
+		JsonFileName:     r.WriteJson,
+		MarkdownFileName: r.WriteMarkdown,
+		JunitFileName:    r.WriteJunit,
+	}
+
+	for name, write := range writers {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		if err := write(file); err != nil {
+			file.Close()
+			return err
+		}
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Report) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes a summary, which can be posted e.g. as a job summary or a merge request comment.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	status := "passed"
+	if !r.Passed {
+		status = "failed"
+	}
+
+	fmt.Fprintf(w, "# Glass Analysis: %s\n\n", r.Module)
+	fmt.Fprintf(w, "Analysis **%s**. Glass %s, Quality Measure version %s.\n\n", status, r.GlassVersion, r.QualityMeasureVersion)
+
+	fmt.Fprintln(w, "| Measure | Value |")
+	fmt.Fprintln(w, "| --- | --- |")
+	fmt.Fprintf(w, "| Original Codebase Size | %d |\n", r.OriginalCodebaseSize)
+	fmt.Fprintf(w, "| Library Codebase Size | %d |\n", r.LibraryCodebaseSize)
+	fmt.Fprintf(w, "| Library Ratio | %.2f |\n", r.LibraryRatio)
+	fmt.Fprintf(w, "| Dependencies | %d |\n", len(r.Libraries))
+	fmt.Fprintf(w, "| Commits | %s |\n", orMissing(r.CommitCount))
+	fmt.Fprintf(w, "| Creation Date | %s |\n", orMissing(r.CreationDate))
+	fmt.Fprintf(w, "| Latest Release | %s |\n", orMissing(r.LatestRelease))
+
+	if len(r.Factors) > 0 {
+		fmt.Fprintf(w, "| Quality Measure | %.2f |\n", r.QualityMeasure)
+
+		for _, f := range quality.Factors {
+			if score, ok := r.Factors[f.Name]; ok {
+				fmt.Fprintf(w, "| Factor: %s | %.2f |\n", f.Name, score)
+			}
+		}
+	}
+
+	if len(r.Checks) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Check | Threshold | Value | Result |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- |")
+
+		for _, c := range r.Checks {
+			result := "pass"
+			if !c.Passed {
+				result = "**fail**"
+			}
+
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", c.Name, c.Threshold, c.Value, result)
+		}
+	}
+
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(w)
+
+		for _, n := range r.Notes {
+			fmt.Fprintf(w, "- %s\n", n)
+		}
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJunit writes the checks as test cases, which CI systems display as test results.
+func (r *Report) WriteJunit(w io.Writer) error {
+	suite := junitTestSuite{Name: "glass", Tests: len(r.Checks)}
+
+	for _, c := range r.Checks {
+		tc := junitTestCase{Name: c.Name, ClassName: "glass." + r.Module}
+
+		if !c.Passed {
+			tc.Failure = &junitFailure{Message: fmt.Sprintf("%s is %s, expected %s", c.Name, c.Value, c.Threshold)}
+			suite.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func orMissing(v interface{}) string {
+	switch v := v.(type) {
+	case int:
+		if v == 0 {
+			return "n/a"
+		}
+	case string:
+		if v == "" {
+			return "n/a"
+		}
+	}
+
+	return fmt.Sprint(v)
+}
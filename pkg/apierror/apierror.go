@@ -0,0 +1,140 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Shared error type of the API handlers. Every error is written to the response in the same envelope:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "fields": [{"field": "repository_url", "code": "repository_url", "message": "..."}]}}
+
+const (
+	CodeBadRequest = "bad_request"
+	CodeNotFound   = "not_found"
+	CodeConflict   = "conflict"
+	CodeValidation = "validation_failed"
+	CodeInternal   = "internal_error"
+)
+
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func BadRequest(format string, a ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeBadRequest, Message: fmt.Sprintf(format, a...)}
+}
+
+func NotFound(format string, a ...interface{}) *Error {
+	return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: fmt.Sprintf(format, a...)}
+}
+
+func Conflict(format string, a ...interface{}) *Error {
+	return &Error{Status: http.StatusConflict, Code: CodeConflict, Message: fmt.Sprintf(format, a...)}
+}
+
+func Validation(fields []FieldError) *Error {
+	return &Error{Status: http.StatusUnprocessableEntity, Code: CodeValidation, Message: "validation failed", Fields: fields}
+}
+
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+}
+
+// From maps an error to an API error: missing rows to 404, unique violations to 409, validation
+// errors to 422, malformed JSON to 400, and everything else to 500.
+func From(err error) *Error {
+	var (
+		apiErr        *Error
+		validationErr validator.ValidationErrors
+		syntaxErr     *json.SyntaxError
+		typeErr       *json.UnmarshalTypeError
+		sqlErr        interface{ SQLState() string }
+	)
+
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("record not found")
+	case errors.As(err, &validationErr):
+		return Validation(Fields(validationErr))
+	case errors.As(err, &typeErr):
+		return Validation([]FieldError{{Field: typeErr.Field, Code: "type", Message: "must be a " + typeErr.Type.String()}})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest("malformed JSON: %s", err.Error())
+	case errors.As(err, &sqlErr) && sqlErr.SQLState() == "23505":
+		return Conflict(err.Error())
+	}
+
+	return Internal(err)
+}
+
+// Fields converts the errors of the validator to field-level messages.
+func Fields(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+
+	for _, e := range errs {
+		fields = append(fields, FieldError{Field: field(e), Code: e.Tag(), Message: message(e)})
+	}
+
+	return fields
+}
+
+// Abort writes the error to the response in the error envelope, and stops the handler chain.
+func Abort(c *gin.Context, err error) {
+	e := From(err)
+
+	c.AbortWithStatusJSON(e.Status, gin.H{"error": e})
+}
+
+// Returns the path of the field without the input struct, e.g. "sources[0].type" of a nested field.
+func field(e validator.FieldError) string {
+	if _, path, ok := strings.Cut(e.Namespace(), "."); ok {
+		return path
+	}
+
+	return e.Field()
+}
+
+func message(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required", "required_if", "required_unless":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "count":
+		return "must be a non-negative integer"
+	case "date":
+		return "must be an RFC 3339 timestamp, a YYYY-MM-DD date or a git date"
+	case "repository_url":
+		return "must be a repository URL without a scheme, e.g. github.com/owner/name"
+	case "seed":
+		return "must be a repository, e.g. github.com/owner/name or https://github.com/owner/name"
+	case "max":
+		return "must be at most " + e.Param() + " characters"
+	}
+
+	return "failed on the '" + e.Tag() + "' rule"
+}
@@ -0,0 +1,447 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/query"
+	"gorm.io/gorm"
+)
+
+// Batch endpoints for creating, updating and deleting many rows in a single transaction.
+//
+// Request body is a JSON array of objects, or a stream of newline-delimited JSON objects.
+// Every item is validated before anything is written. If any of the items is invalid, nothing
+// is written, and the response lists the result of every item.
+
+const (
+	// Maximum amount of items in a single request.
+	MaxItems = 10000
+
+	// Amount of rows inserted, or looked up for conflicts, with a single statement.
+	BatchSize = 500
+)
+
+const (
+	StatusCreated  = "created"
+	StatusUpdated  = "updated"
+	StatusValid    = "valid"
+	StatusInvalid  = "invalid"
+	StatusNotFound = "not_found"
+	StatusConflict = "conflict"
+)
+
+type Result struct {
+	Index  int                   `json:"index"`
+	Id     int                   `json:"id,omitempty"`
+	Status string                `json:"status"`
+	Errors []apierror.FieldError `json:"errors,omitempty"`
+}
+
+// Returned from the transaction to roll it back, when an item fails.
+var errRollback = errors.New("rollback")
+
+// Create validates the items as In, converts them to rows with convert, and inserts them. Rows,
+// which have the natural key of an existing row, or of an earlier item, are conflicts, the same
+// as in the single creates, and nothing is inserted.
+func Create[In any, Out any](c *gin.Context, db *gorm.DB, key []string, convert func(In) Out) {
+	items, err := readItems(c.Request.Body)
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("%s", err))
+		return
+	}
+
+	results := make([]Result, len(items))
+	rows := make([]Out, 0, len(items))
+	valid := true
+
+	for i, item := range items {
+		var in In
+
+		results[i] = Result{Index: i, Status: StatusValid}
+
+		if err := decode(item, &in); err != nil {
+			results[i].Status = StatusInvalid
+			results[i].Errors = itemErrors(err)
+			valid = false
+
+			continue
+		}
+
+		rows = append(rows, convert(in))
+	}
+
+	if !valid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"data": results})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		conflicts, err := findConflicts(tx, key, rows, results)
+		if err != nil {
+			return err
+		}
+
+		if conflicts {
+			return errRollback
+		}
+
+		return tx.CreateInBatches(&rows, BatchSize).Error
+	})
+
+	switch {
+	case errors.Is(err, errRollback):
+		c.JSON(http.StatusConflict, gin.H{"data": results})
+		return
+	case err != nil:
+		apierror.Abort(c, err)
+		return
+	}
+
+	for i := range rows {
+		results[i].Id = id(&rows[i])
+		results[i].Status = StatusCreated
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// Update validates the items as In, and updates the fields present in the items to the rows
+// matching the "id" of the items. Fields can also be set to empty values.
+func Update[In any, Out any](c *gin.Context, db *gorm.DB) {
+	items, err := readItems(c.Request.Body)
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("%s", err))
+		return
+	}
+
+	columns := make(map[string]bool)
+
+	for _, column := range models.Columns(new(Out)) {
+		columns[column] = column != "id"
+	}
+
+	results := make([]Result, len(items))
+	updates := make([]map[string]interface{}, len(items))
+	valid := true
+
+	for i, item := range items {
+		results[i] = Result{Index: i, Status: StatusValid}
+
+		rowId, fields, err := decodeUpdate[In](item, columns)
+		if err != nil {
+			results[i].Status = StatusInvalid
+			results[i].Errors = itemErrors(err)
+			valid = false
+
+			continue
+		}
+
+		results[i].Id = rowId
+		updates[i] = fields
+	}
+
+	if !valid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"data": results})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		failed := false
+
+		for i, fields := range updates {
+			var count int64
+
+			if err := tx.Model(new(Out)).Where("id = ?", results[i].Id).Count(&count).Error; err != nil {
+				return err
+			}
+
+			if count == 0 {
+				results[i].Status = StatusNotFound
+				failed = true
+
+				continue
+			}
+
+			if err := tx.Model(new(Out)).Where("id = ?", results[i].Id).Updates(fields).Error; err != nil {
+				return err
+			}
+
+			results[i].Status = StatusUpdated
+		}
+
+		if failed {
+			return errRollback
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errRollback):
+		// Updates of the other items were rolled back as well.
+		for i := range results {
+			if results[i].Status == StatusUpdated {
+				results[i].Status = StatusValid
+			}
+		}
+
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"data": results})
+	case err != nil:
+		apierror.Abort(c, err)
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": results})
+	}
+}
+
+// Delete removes the rows matching the filters of the query parameters, with the same syntax as
+// the list endpoints. Deleting every row requires "all=true", so a missing filter can't empty the table.
+func Delete[T any](c *gin.Context, db *gorm.DB, r query.Resource) {
+	q, err := query.Parse(c, r)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	if !q.HasFilters() {
+		if c.Query("all") != "true" {
+			apierror.Abort(c, apierror.BadRequest("bulk delete requires a filter, or all=true"))
+			return
+		}
+
+		db = db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+
+	result := db.Scopes(q.Filter).Delete(new(T))
+	if result.Error != nil {
+		apierror.Abort(c, result.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"deleted": result.RowsAffected}})
+}
+
+// Marks the rows, which have the natural key of an existing row, or of an earlier row, as
+// conflicts. Returns true, if any of the rows is a conflict.
+func findConflicts[Out any](tx *gorm.DB, key []string, rows []Out, results []Result) (bool, error) {
+	found := false
+	first := make(map[string]int, len(rows))
+
+	var unique []int
+
+	for i := range rows {
+		k := strings.Join(models.Values(&rows[i], key), "\x00")
+
+		if j, ok := first[k]; ok {
+			results[i].Status = StatusConflict
+			results[i].Errors = conflictErrors(key, fmt.Sprintf("is the same as in item %d", j))
+			found = true
+
+			continue
+		}
+
+		first[k] = i
+		unique = append(unique, i)
+	}
+
+	existing, err := existingKeys(tx, key, rows, unique)
+	if err != nil {
+		return false, err
+	}
+
+	for _, i := range unique {
+		if existing[strings.Join(models.Values(&rows[i], key), "\x00")] {
+			results[i].Status = StatusConflict
+			results[i].Errors = conflictErrors(key, "already exists")
+			found = true
+		}
+	}
+
+	return found, nil
+}
+
+// Returns the natural keys of the rows of the indexes, which exist in the database, joined the
+// same way as in findConflicts. Keys are looked up with a query per batch.
+func existingKeys[Out any](tx *gorm.DB, key []string, rows []Out, indexes []int) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	columns := "(" + strings.Join(key, ", ") + ") IN ?"
+
+	for start := 0; start < len(indexes); start += BatchSize {
+		batch := indexes[start:min(start+BatchSize, len(indexes))]
+		tuples := make([][]interface{}, len(batch))
+
+		for i, index := range batch {
+			for _, value := range models.Values(&rows[index], key) {
+				tuples[i] = append(tuples[i], value)
+			}
+		}
+
+		var found []Out
+
+		if err := tx.Model(new(Out)).Select(key).Where(columns, tuples).Find(&found).Error; err != nil {
+			return nil, err
+		}
+
+		for i := range found {
+			existing[strings.Join(models.Values(&found[i], key), "\x00")] = true
+		}
+	}
+
+	return existing, nil
+}
+
+func conflictErrors(key []string, message string) []apierror.FieldError {
+	errs := make([]apierror.FieldError, len(key))
+
+	for i, column := range key {
+		errs[i] = apierror.FieldError{Field: column, Code: apierror.CodeConflict, Message: message}
+	}
+
+	return errs
+}
+
+// Reads the items of a JSON array, or of a stream of JSON objects.
+func readItems(r io.Reader) ([]json.RawMessage, error) {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+
+	first, err := firstByte(reader)
+	if err != nil {
+		return nil, errors.New("request body is empty")
+	}
+
+	var items []json.RawMessage
+
+	if first == '[' {
+		if err := decoder.Decode(&items); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+	} else {
+		for {
+			var item json.RawMessage
+
+			err := decoder.Decode(&item)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON on item %d: %w", len(items), err)
+			}
+
+			items = append(items, item)
+
+			if len(items) > MaxItems {
+				break
+			}
+		}
+	}
+
+	if len(items) > MaxItems {
+		return nil, fmt.Errorf("too many items, maximum is %d", MaxItems)
+	}
+
+	return items, nil
+}
+
+// Returns the first non-whitespace byte of the reader, without consuming it.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// Decodes an item strictly, and validates it with the "binding" -tags of the struct.
+func decode(item json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(item))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		// Unknown fields and other decoding errors, which aren't mapped to a field, are bad requests.
+		if e := apierror.From(err); e.Code != apierror.CodeInternal {
+			return e
+		}
+
+		return apierror.BadRequest("%s", err)
+	}
+
+	return binding.Validator.ValidateStruct(v)
+}
+
+// Decodes an update item. Returns the id of the row, and the values of the fields present in the item.
+func decodeUpdate[In any](item json.RawMessage, columns map[string]bool) (int, map[string]interface{}, error) {
+	var raw map[string]json.RawMessage
+
+	if err := json.Unmarshal(item, &raw); err != nil {
+		return 0, nil, apierror.BadRequest("item must be a JSON object: %s", err)
+	}
+
+	rowId, err := strconv.Atoi(string(raw["id"]))
+	if err != nil || rowId < 1 {
+		return 0, nil, apierror.Validation([]apierror.FieldError{{Field: "id", Code: "required", Message: "must be a positive integer"}})
+	}
+
+	delete(raw, "id")
+
+	var present []string
+
+	for field := range raw {
+		if !columns[field] {
+			return 0, nil, apierror.Validation([]apierror.FieldError{{Field: field, Code: "unknown", Message: "is not a field of the resource"}})
+		}
+
+		present = append(present, field)
+	}
+
+	if len(present) == 0 {
+		return 0, nil, apierror.BadRequest("no fields to update")
+	}
+
+	fields, _ := json.Marshal(raw)
+
+	var in In
+
+	if err := decode(fields, &in); err != nil {
+		return 0, nil, err
+	}
+
+	return rowId, models.Fields(&in, present), nil
+}
+
+// Converts the error of an item to the errors of the result. Errors without fields are returned as a
+// single error, with an empty field.
+func itemErrors(err error) []apierror.FieldError {
+	e := apierror.From(err)
+
+	if len(e.Fields) > 0 {
+		return e.Fields
+	}
+
+	return []apierror.FieldError{{Code: e.Code, Message: e.Message}}
+}
+
+func id(row interface{}) int {
+	rowId, _ := strconv.Atoi(models.Values(row, []string{"id"})[0])
+	return rowId
+}
@@ -0,0 +1,292 @@
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var repositories = query.Resource{
+	Model:   models.Repository{},
+	Numeric: []string{"stargazer_count"},
+}
+
+func newDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Repository{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newContext(method string, target string, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+
+	return c, w
+}
+
+func newRepository(i models.CreateRepositoryInput) models.Repository {
+	return models.Repository{RepositoryName: i.RepositoryName, RepositoryUrl: i.RepositoryUrl, StargazerCount: i.StargazerCount, PrimaryLanguage: i.PrimaryLanguage}
+}
+
+func decodeResults(t *testing.T, w *httptest.ResponseRecorder) []Result {
+	t.Helper()
+
+	var response struct {
+		Data []Result `json:"data"`
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s: %s", err, w.Body)
+	}
+
+	return response.Data
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		statuses []string
+	}{
+		{
+			name:     "array",
+			body:     `[{"repository_name": "github.com/o/a", "repository_url": "github.com/o/a"}, {"repository_name": "github.com/o/b", "repository_url": "github.com/o/b", "stargazer_count": "5"}]`,
+			code:     http.StatusOK,
+			statuses: []string{StatusCreated, StatusCreated},
+		},
+		{
+			name:     "stream",
+			body:     "{\"repository_name\": \"github.com/o/a\", \"repository_url\": \"github.com/o/a\"}\n{\"repository_name\": \"github.com/o/b\", \"repository_url\": \"github.com/o/b\"}\n",
+			code:     http.StatusOK,
+			statuses: []string{StatusCreated, StatusCreated},
+		},
+		{
+			name:     "invalid item",
+			body:     `[{"repository_name": "github.com/o/a", "repository_url": "github.com/o/a"}, {"repository_name": "github.com/o/b", "repository_url": "github.com/o/b", "stargazer_count": "many"}]`,
+			code:     http.StatusUnprocessableEntity,
+			statuses: []string{StatusValid, StatusInvalid},
+		},
+		{
+			name:     "missing required field",
+			body:     `[{"stargazer_count": "5"}]`,
+			code:     http.StatusUnprocessableEntity,
+			statuses: []string{StatusInvalid},
+		},
+		{
+			name:     "unknown field",
+			body:     `[{"repository_name": "github.com/o/a", "repository_url": "github.com/o/a", "stars": "5"}]`,
+			code:     http.StatusUnprocessableEntity,
+			statuses: []string{StatusInvalid},
+		},
+		{
+			name:     "duplicate in the request",
+			body:     `[{"repository_name": "github.com/o/a", "repository_url": "github.com/o/a"}, {"repository_name": "github.com/o/b", "repository_url": "github.com/o/b"}, {"repository_name": "github.com/o/a", "repository_url": "github.com/o/a"}]`,
+			code:     http.StatusConflict,
+			statuses: []string{StatusValid, StatusValid, StatusConflict},
+		},
+		{
+			name:     "same short name",
+			body:     `[{"repository_name": "name", "repository_url": "github.com/a/name"}, {"repository_name": "name", "repository_url": "github.com/b/name"}]`,
+			code:     http.StatusOK,
+			statuses: []string{StatusCreated, StatusCreated},
+		},
+		{
+			name:     "duplicate in the database",
+			body:     `[{"repository_name": "github.com/o/a", "repository_url": "github.com/o/a"}, {"repository_name": "github.com/o/existing", "repository_url": "github.com/o/existing"}]`,
+			code:     http.StatusConflict,
+			statuses: []string{StatusValid, StatusConflict},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDatabase(t)
+			db.Create(&models.Repository{RepositoryName: "github.com/o/existing", RepositoryUrl: "github.com/o/existing"})
+
+			c, w := newContext(http.MethodPost, "/", tt.body)
+			Create(c, db, models.RepositoryKey, newRepository)
+
+			if w.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+
+			results := decodeResults(t, w)
+			if len(results) != len(tt.statuses) {
+				t.Fatalf("results = %d, want %d: %s", len(results), len(tt.statuses), w.Body)
+			}
+
+			for i, r := range results {
+				if r.Status != tt.statuses[i] {
+					t.Errorf("status of item %d = %q, want %q: %v", i, r.Status, tt.statuses[i], r.Errors)
+				}
+
+				if r.Status == StatusConflict && (len(r.Errors) == 0 || r.Errors[0].Code != StatusConflict) {
+					t.Errorf("errors of item %d = %v, want a conflict", i, r.Errors)
+				}
+			}
+
+			var count int64
+			db.Model(&models.Repository{}).Count(&count)
+
+			want := int64(1)
+			if tt.code == http.StatusOK {
+				want += int64(len(tt.statuses))
+			}
+
+			if count != want {
+				t.Errorf("repositories = %d, want %d", count, want)
+			}
+		})
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	db := newDatabase(t)
+
+	if err := db.AutoMigrate(&models.Commit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Create(&[]models.Commit{
+		{RepositoryName: "github.com/o/a", CommitDate: "2023-01-01", CommitUser: "u"},
+		{RepositoryName: "github.com/o/b", CommitDate: "2023-01-01", CommitUser: "u"},
+	})
+
+	queries := 0
+	db.Callback().Query().Before("gorm:query").Register("count", func(*gorm.DB) { queries++ })
+
+	rows := make([]models.Commit, 2*BatchSize+2)
+
+	for i := range rows {
+		rows[i] = models.Commit{RepositoryName: "github.com/o/a", CommitDate: "2023-01-01", CommitUser: fmt.Sprint(i)}
+	}
+
+	// The same date and user as an existing commit of another repository.
+	rows[1].RepositoryName, rows[1].CommitUser = "github.com/o/c", "u"
+	rows[BatchSize+1].CommitUser = "u"
+	rows[2*BatchSize+1] = rows[0]
+
+	results := make([]Result, len(rows))
+
+	found, err := findConflicts(db, models.CommitKey, rows, results)
+	if err != nil || !found {
+		t.Fatalf("findConflicts = %v, %v, want conflicts", found, err)
+	}
+
+	for i, r := range results {
+		if want := i == BatchSize+1 || i == 2*BatchSize+1; (r.Status == StatusConflict) != want {
+			t.Errorf("status of row %d = %q, want conflict %v", i, r.Status, want)
+		}
+	}
+
+	if queries != 3 {
+		t.Errorf("queries = %d, want 3, a query per batch", queries)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		statuses []string
+		stars    string
+	}{
+		{"update", `[{"id": 1, "stargazer_count": "7"}]`, http.StatusOK, []string{StatusUpdated}, "7"},
+		{"empty value", `[{"id": 1, "stargazer_count": ""}]`, http.StatusOK, []string{StatusUpdated}, ""},
+		{"missing row", `[{"id": 1, "stargazer_count": "7"}, {"id": 99, "stargazer_count": "7"}]`, http.StatusUnprocessableEntity, []string{StatusValid, StatusNotFound}, "3"},
+		{"missing id", `[{"stargazer_count": "7"}]`, http.StatusUnprocessableEntity, []string{StatusInvalid}, "3"},
+		{"unknown field", `[{"id": 1, "stars": "7"}]`, http.StatusUnprocessableEntity, []string{StatusInvalid}, "3"},
+		{"invalid value", `[{"id": 1, "stargazer_count": "many"}]`, http.StatusUnprocessableEntity, []string{StatusInvalid}, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDatabase(t)
+			db.Create(&models.Repository{RepositoryName: "github.com/o/a", StargazerCount: "3"})
+
+			c, w := newContext(http.MethodPatch, "/", tt.body)
+			Update[models.UpdateRepositoryInput, models.Repository](c, db)
+
+			if w.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+
+			for i, r := range decodeResults(t, w) {
+				if r.Status != tt.statuses[i] {
+					t.Errorf("status of item %d = %q, want %q: %v", i, r.Status, tt.statuses[i], r.Errors)
+				}
+			}
+
+			var repository models.Repository
+			db.First(&repository, 1)
+
+			if repository.StargazerCount != tt.stars {
+				t.Errorf("stargazer_count = %q, want %q", repository.StargazerCount, tt.stars)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		code    int
+		deleted int64
+	}{
+		{"filter", "/?primary_language=Go", http.StatusOK, 2},
+		{"comparison filter", "/?stargazer_count>=5", http.StatusOK, 1},
+		{"every row", "/?all=true", http.StatusOK, 3},
+		{"without a filter", "/", http.StatusBadRequest, 0},
+		{"misspelled filter", "/?primary_language=Go&licnse_info=mit", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDatabase(t)
+			db.Create(&[]models.Repository{
+				{RepositoryName: "github.com/o/a", PrimaryLanguage: "Go", StargazerCount: "10"},
+				{RepositoryName: "github.com/o/b", PrimaryLanguage: "Go"},
+				{RepositoryName: "github.com/o/c", PrimaryLanguage: "Rust"},
+			})
+
+			c, w := newContext(http.MethodDelete, tt.target, "")
+			Delete[models.Repository](c, db, repositories)
+
+			if w.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+
+			var count int64
+			db.Model(&models.Repository{}).Count(&count)
+
+			if deleted := 3 - count; deleted != tt.deleted {
+				t.Errorf("deleted = %d, want %d", deleted, tt.deleted)
+			}
+		})
+	}
+}
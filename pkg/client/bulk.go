@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/bulk"
+)
+
+// BulkError is returned, when any item of a bulk request is invalid, missing or a conflict. Nothing
+// was written.
+type BulkError struct {
+	Results []bulk.Result
+}
+
+func (e *BulkError) Error() string {
+	failed := 0
+
+	for _, r := range e.Results {
+		if r.Status == bulk.StatusInvalid || r.Status == bulk.StatusNotFound || r.Status == bulk.StatusConflict {
+			failed++
+		}
+	}
+
+	return fmt.Sprintf("bulk request failed: %d of %d items are invalid, missing or conflicts", failed, len(e.Results))
+}
+
+func bulkWrite[T any](ctx context.Context, c *Client, method string, path string, items []T) ([]bulk.Result, error) {
+	body, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	r := request{
+		Method:      method,
+		Path:        path,
+		Body:        body,
+		ContentType: "application/json",
+		Idempotent:  method != http.MethodPost,
+		Statuses:    []int{http.StatusUnprocessableEntity, http.StatusConflict},
+	}
+
+	res, err := c.do(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var out struct {
+		Data []bulk.Result `json:"data"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnprocessableEntity || res.StatusCode == http.StatusConflict {
+		return out.Data, &BulkError{Results: out.Data}
+	}
+
+	return out.Data, nil
+}
+
+func bulkDelete(ctx context.Context, c *Client, path string, filters []Filter, all bool) (int64, error) {
+	q := ListOptions{Filters: filters}.values()
+
+	if all {
+		q.Set("all", "true")
+	}
+
+	var out struct {
+		Data struct {
+			Deleted int64 `json:"deleted"`
+		} `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodDelete, path, q, nil, &out); err != nil {
+		return 0, err
+	}
+
+	return out.Data.Deleted, nil
+}
+
+// Query parameters of the bool options, which are only sent when true.
+func boolParam(q url.Values, key string, value bool) {
+	if value {
+		q.Set(key, strconv.FormatBool(value))
+	}
+}
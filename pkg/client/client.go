@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/apierror"
+)
+
+// Client of the Glass API. Methods take a context, which cancels the request and the retries.
+//
+// Requests, which fail with a network error or with 429, 502, 503 or 504, are retried with
+// an exponential backoff, if the request is idempotent. Errors of the API are returned as
+// *apierror.Error, with the status of the response.
+
+const (
+	DefaultTimeout    = 5 * time.Minute
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 500 * time.Millisecond
+)
+
+type Client struct {
+	// Base URL of the API, e.g. "http://localhost:8080/api/glass/v1".
+	BaseUrl    string
+	HttpClient *http.Client
+
+	// Amount of retries after the first attempt, and the delay before the first retry,
+	// which doubles on every retry.
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+func NewClient(baseUrl string) *Client {
+	c := new(Client)
+
+	c.BaseUrl = strings.TrimSuffix(baseUrl, "/")
+	c.HttpClient = &http.Client{Timeout: DefaultTimeout}
+	c.MaxRetries = DefaultMaxRetries
+	c.Backoff = DefaultBackoff
+
+	return c
+}
+
+// Request of the client. Body is kept in memory, so the request can be sent again on retries.
+type request struct {
+	Method      string
+	Path        string
+	Query       url.Values
+	Body        []byte
+	ContentType string
+
+	// Requests, which are safe to send more than once.
+	Idempotent bool
+
+	// Statuses besides 2xx, which are returned as responses instead of errors.
+	Statuses []int
+}
+
+// Sends the request as JSON, and decodes the JSON response to out, unless out is nil.
+func (c *Client) doJson(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) error {
+	r := request{Method: method, Path: path, Query: query, Idempotent: method != http.MethodPost}
+
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		r.Body = body
+		r.ContentType = "application/json"
+	}
+
+	res, err := c.do(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Sends the request, and returns the response, if the status is 2xx. The caller closes the body.
+func (c *Client) do(ctx context.Context, r request) (*http.Response, error) {
+	u := c.BaseUrl + r.Path
+	if len(r.Query) > 0 {
+		u += "?" + r.Query.Encode()
+	}
+
+	backoff := c.Backoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.Method, u, bytes.NewReader(r.Body))
+		if err != nil {
+			return nil, err
+		}
+
+		if r.ContentType != "" {
+			req.Header.Set("Content-Type", r.ContentType)
+		}
+
+		req.Header.Set("Accept", "application/json")
+
+		res, err := c.HttpClient.Do(req)
+
+		retry := r.Idempotent && attempt < c.MaxRetries && ctx.Err() == nil
+
+		switch {
+		case err != nil && !retry:
+			return nil, err
+		case err != nil:
+			// Network errors are retried.
+		case res.StatusCode < 300 || r.accepts(res.StatusCode):
+			return res, nil
+		case retry && retryable(res.StatusCode):
+			if d := retryAfter(res); d > 0 {
+				backoff = d
+			}
+
+			res.Body.Close()
+		default:
+			defer res.Body.Close()
+
+			return nil, decodeError(res)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (r request) accepts(status int) bool {
+	for _, s := range r.Statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// Parses the "Retry-After" header in seconds.
+func retryAfter(res *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// Decodes the error envelope of the response. Responses without the envelope are returned as
+// errors with the status and the body as the message.
+func decodeError(res *http.Response) error {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Error *apierror.Error `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error == nil {
+		return &apierror.Error{Status: res.StatusCode, Message: fmt.Sprintf("%s: %s", res.Status, strings.TrimSpace(string(body)))}
+	}
+
+	envelope.Error.Status = res.StatusCode
+
+	return envelope.Error
+}
+
+// IsNotFound reports, if the error is a 404 of the API.
+func IsNotFound(err error) bool {
+	var e *apierror.Error
+
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// IsConflict reports, if the error is a 409 of the API.
+func IsConflict(err error) bool {
+	var e *apierror.Error
+
+	return errors.As(err, &e) && e.Status == http.StatusConflict
+}
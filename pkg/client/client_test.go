@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/openapi"
+	"github.com/haapjari/glass/pkg/router"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Returns a client of the API of the router, which fails the first requests with the statuses
+// of fail, and the database of the API.
+func newClient(t *testing.T, fail ...int) (*Client, *gorm.DB, *atomic.Int32) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	r := router.NewRouter(ctx, db, &config.Config{})
+	requests := new(atomic.Int32)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(requests.Add(1))
+
+		if n <= len(fail) {
+			if fail[n-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+
+			http.Error(w, http.StatusText(fail[n-1]), fail[n-1])
+
+			return
+		}
+
+		r.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL + openapi.BasePath)
+	c.Backoff = time.Millisecond
+
+	return c, db, requests
+}
+
+func TestIterator(t *testing.T) {
+	c, db, requests := newClient(t)
+
+	for i := 0; i < 7; i++ {
+		db.Create(&models.Repository{RepositoryName: fmt.Sprintf("github.com/o/r%d", i), StargazerCount: fmt.Sprint(i % 3)})
+	}
+
+	it := c.Repositories(context.Background(), ListOptions{Sort: []string{"-stargazer_count"}, Limit: 2})
+
+	seen := map[int]bool{}
+	previous := 3
+
+	for it.Next() {
+		r := it.Value()
+
+		if seen[r.Id] {
+			t.Errorf("repository %d is on many pages", r.Id)
+		}
+
+		seen[r.Id] = true
+
+		var stars int
+		fmt.Sscan(r.StargazerCount, &stars)
+
+		if stars > previous {
+			t.Errorf("repository %d is out of order", r.Id)
+		}
+
+		previous = stars
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 7 {
+		t.Errorf("iterated %d repositories, want 7", len(seen))
+	}
+
+	if n := requests.Load(); n != 4 {
+		t.Errorf("requests = %d, want 4 pages", n)
+	}
+}
+
+func TestListLinks(t *testing.T) {
+	c, db, _ := newClient(t)
+
+	for i := 0; i < 3; i++ {
+		db.Create(&models.Repository{RepositoryName: fmt.Sprintf("github.com/o/r%d", i), PrimaryLanguage: "Go"})
+	}
+
+	o := ListOptions{Filters: []Filter{{Column: "primary_language", Operator: Equal, Value: "Go"}}, Limit: 2}
+
+	page, err := c.ListRepositories(context.Background(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Data) != 2 || page.Meta.NextCursor == "" || page.Links.Next == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	o.Cursor = page.Meta.NextCursor
+
+	page, err = c.ListRepositories(context.Background(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Data) != 1 || page.Meta.NextCursor != "" || page.Links.Next != "" {
+		t.Errorf("last page = %+v", page)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     []int
+		create   bool
+		requests int32
+		status   int
+	}{
+		{"no failures", nil, false, 1, 0},
+		{"too many requests", []int{http.StatusTooManyRequests}, false, 2, 0},
+		{"unavailable", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}, false, 4, 0},
+		{"retries run out", []int{503, 503, 503, 503, 503}, false, DefaultMaxRetries + 1, http.StatusServiceUnavailable},
+		{"internal error", []int{http.StatusInternalServerError}, false, 1, http.StatusInternalServerError},
+		{"create isn't idempotent", []int{http.StatusServiceUnavailable}, true, 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, requests := newClient(t, tt.fail...)
+
+			var err error
+
+			if tt.create {
+				_, err = c.CreateRepository(context.Background(), models.CreateRepositoryInput{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a"})
+			} else {
+				_, err = c.ListRepositories(context.Background(), ListOptions{})
+			}
+
+			var e *apierror.Error
+
+			switch {
+			case tt.status == 0 && err != nil:
+				t.Errorf("error = %v, want none", err)
+			case tt.status != 0 && (!errors.As(err, &e) || e.Status != tt.status):
+				t.Errorf("error = %v, want status %d", err, tt.status)
+			}
+
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("requests = %d, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetriesAreCancelled(t *testing.T) {
+	c, _, requests := newClient(t, 503, 503, 503)
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.ListRepositories(ctx, ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the deadline", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, db, _ := newClient(t)
+	ctx := context.Background()
+
+	db.Create(&models.Repository{RepositoryName: "github.com/o/existing", RepositoryUrl: "github.com/o/existing"})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := c.GetRepository(ctx, 99)
+
+		if !IsNotFound(err) {
+			t.Errorf("error = %v, want not found", err)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := c.CreateRepository(ctx, models.CreateRepositoryInput{RepositoryName: "github.com/o/existing", RepositoryUrl: "github.com/o/existing"})
+
+		var e *apierror.Error
+		if !IsConflict(err) || !errors.As(err, &e) || e.Code != apierror.CodeConflict {
+			t.Errorf("error = %#v, want a conflict", err)
+		}
+	})
+
+	t.Run("same short name", func(t *testing.T) {
+		db.Create(&models.Repository{RepositoryName: "name", RepositoryUrl: "github.com/a/name"})
+
+		if _, err := c.CreateRepository(ctx, models.CreateRepositoryInput{RepositoryName: "name", RepositoryUrl: "github.com/b/name"}); err != nil {
+			t.Errorf("error = %v, want none", err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := c.CreateRepository(ctx, models.CreateRepositoryInput{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "many"})
+
+		var e *apierror.Error
+		if !errors.As(err, &e) || e.Status != http.StatusUnprocessableEntity || len(e.Fields) != 1 || e.Fields[0].Field != "stargazer_count" {
+			t.Errorf("error = %#v, want a validation error of stargazer_count", err)
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		_, err := c.ListRepositories(ctx, ListOptions{Sort: []string{"stars"}})
+
+		var e *apierror.Error
+		if !errors.As(err, &e) || e.Status != http.StatusBadRequest || e.Message == "" {
+			t.Errorf("error = %#v, want a bad request", err)
+		}
+	})
+
+	t.Run("bulk conflict", func(t *testing.T) {
+		results, err := c.BulkCreateRepositories(ctx, []models.CreateRepositoryInput{{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a"}, {RepositoryName: "github.com/o/existing", RepositoryUrl: "github.com/o/existing"}})
+
+		var e *BulkError
+		if !errors.As(err, &e) {
+			t.Fatalf("error = %v, want a BulkError", err)
+		}
+
+		if len(results) != 2 || results[1].Status != bulk.StatusConflict {
+			t.Errorf("results = %+v, want a conflict of the second item", results)
+		}
+	})
+
+	t.Run("bulk delete without a filter", func(t *testing.T) {
+		_, err := c.BulkDeleteRepositories(ctx, nil, false)
+
+		var e *apierror.Error
+		if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+			t.Errorf("error = %v, want a bad request", err)
+		}
+	})
+}
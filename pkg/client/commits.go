@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+const commitPath = "/commit"
+
+func (c *Client) ListCommits(ctx context.Context, o ListOptions) (*Page[models.Commit], error) {
+	return list[models.Commit](ctx, c, commitPath, o)
+}
+
+// Commits iterates every commit matching the options.
+func (c *Client) Commits(ctx context.Context, o ListOptions) *Iterator[models.Commit] {
+	return newIterator[models.Commit](ctx, c, commitPath, o)
+}
+
+func (c *Client) GetCommit(ctx context.Context, id int) (*models.Commit, error) {
+	var res struct {
+		Data models.Commit `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodGet, commitPath+"/"+strconv.Itoa(id), nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+func (c *Client) CreateCommit(ctx context.Context, i models.CreateCommitInput) (*models.Commit, error) {
+	var res struct {
+		Data models.Commit `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPost, commitPath, nil, i, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// UpdateCommit updates the non-empty fields of the input.
+func (c *Client) UpdateCommit(ctx context.Context, id int, i models.UpdateCommitInput) (*models.Commit, error) {
+	var res struct {
+		Data models.Commit `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPatch, commitPath+"/"+strconv.Itoa(id), nil, i, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+func (c *Client) DeleteCommit(ctx context.Context, id int) error {
+	return c.doJson(ctx, http.MethodDelete, commitPath+"/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// BulkCreateCommits creates the commits in a single transaction. If any of the items is invalid or
+// a conflict, nothing is created, and the error is returned with the results of every item.
+func (c *Client) BulkCreateCommits(ctx context.Context, items []models.CreateCommitInput) ([]bulk.Result, error) {
+	return bulkWrite(ctx, c, http.MethodPost, commitPath+"/bulk", items)
+}
+
+// BulkUpdateCommits updates the fields present in the items. Every item requires "id".
+func (c *Client) BulkUpdateCommits(ctx context.Context, items []map[string]interface{}) ([]bulk.Result, error) {
+	return bulkWrite(ctx, c, http.MethodPatch, commitPath+"/bulk", items)
+}
+
+// BulkDeleteCommits deletes the commits matching the filters. Deleting every commit requires all to be true.
+func (c *Client) BulkDeleteCommits(ctx context.Context, filters []Filter, all bool) (int64, error) {
+	return bulkDelete(ctx, c, commitPath+"/bulk", filters, all)
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/importer"
+)
+
+// Export downloads a .tar.gz -bundle of the whole dataset. The caller closes the reader.
+func (c *Client) Export(ctx context.Context, format export.Format) (io.ReadCloser, error) {
+	q := url.Values{}
+
+	if format != "" {
+		q.Set("format", string(format))
+	}
+
+	res, err := c.do(ctx, request{Method: http.MethodGet, Path: "/export", Query: q, Idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// Import merges a bundle to the database, and returns the report of the changes.
+func (c *Client) Import(ctx context.Context, bundle io.Reader, o importer.Options) (*importer.Report, error) {
+	body, err := io.ReadAll(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+
+	boolParam(q, "dry_run", o.DryRun)
+
+	if o.OnConflict != "" {
+		q.Set("on_conflict", o.OnConflict)
+	}
+
+	// Dry-runs don't write anything, so they can be retried.
+	res, err := c.do(ctx, request{Method: http.MethodPost, Path: "/import", Query: q, Body: body, ContentType: "application/gzip", Idempotent: o.DryRun})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var out struct {
+		Data importer.Report `json:"data"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+
+	return &out.Data, nil
+}
+
+// RepositoriesCsv streams the repositories matching the filters as CSV. Empty columns returns every
+// column. The caller closes the reader.
+func (c *Client) RepositoriesCsv(ctx context.Context, columns []string, filters []Filter) (io.ReadCloser, error) {
+	return c.csv(ctx, repositoryPath+"/csv", columns, filters)
+}
+
+// CommitsCsv streams the commits matching the filters as CSV. Empty columns returns every column.
+// The caller closes the reader.
+func (c *Client) CommitsCsv(ctx context.Context, columns []string, filters []Filter) (io.ReadCloser, error) {
+	return c.csv(ctx, commitPath+"/csv", columns, filters)
+}
+
+func (c *Client) csv(ctx context.Context, path string, columns []string, filters []Filter) (io.ReadCloser, error) {
+	q := ListOptions{Filters: filters}.values()
+
+	if len(columns) > 0 {
+		q.Set("columns", strings.Join(columns, ","))
+	}
+
+	res, err := c.do(ctx, request{Method: http.MethodGet, Path: path, Query: q, Idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// OpenApi returns the OpenAPI specification of the server.
+func (c *Client) OpenApi(ctx context.Context) (json.RawMessage, error) {
+	res, err := c.do(ctx, request{Method: http.MethodGet, Path: "/openapi.json", Idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return io.ReadAll(res.Body)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/models"
+)
+
+const failurePath = "/failures"
+
+func (c *Client) ListFailures(ctx context.Context, o ListOptions) (*Page[models.Failure], error) {
+	return list[models.Failure](ctx, c, failurePath, o)
+}
+
+func (c *Client) GetFailure(ctx context.Context, id int) (*models.Failure, error) {
+	var res struct {
+		Data models.Failure `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodGet, failurePath+"/"+strconv.Itoa(id), nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// RetryFailure queues a job, which runs the stage of the failure again. Poll GetJob for the status.
+func (c *Client) RetryFailure(ctx context.Context, id int) (*models.Job, error) {
+	var res struct {
+		Data models.Job `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPost, failurePath+"/"+strconv.Itoa(id)+"/retry", nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// RetryFailures queues a job for every plugin and stage of the failures matching the filters.
+// Empty filters retry every failure.
+func (c *Client) RetryFailures(ctx context.Context, filters []Filter) ([]models.Job, error) {
+	var res struct {
+		Data []models.Job `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPost, failurePath+"/retry", ListOptions{Filters: filters}.values(), nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Data, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/models"
+)
+
+const jobPath = "/job"
+
+func (c *Client) ListJobs(ctx context.Context, o ListOptions) (*Page[models.Job], error) {
+	return list[models.Job](ctx, c, jobPath, o)
+}
+
+// ListJobLogs lists the output of the commands, which the job ran for the repositories.
+func (c *Client) ListJobLogs(ctx context.Context, id int, o ListOptions) (*Page[models.JobLog], error) {
+	return list[models.JobLog](ctx, c, jobPath+"/"+strconv.Itoa(id)+"/logs", o)
+}
+
+func (c *Client) GetJob(ctx context.Context, id int) (*models.Job, error) {
+	var res struct {
+		Data models.Job `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodGet, jobPath+"/"+strconv.Itoa(id), nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// CreateJob queues a job, which runs every stage of the plugin. Poll GetJob for the status.
+func (c *Client) CreateJob(ctx context.Context, i models.CreateJobInput) (*models.Job, error) {
+	var res struct {
+		Data models.Job `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPost, jobPath, nil, i, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
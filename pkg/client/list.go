@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/query"
+)
+
+// Operators of the filters, see pkg/query.
+const (
+	Equal          = "="
+	NotEqual       = "!="
+	Greater        = ">"
+	GreaterOrEqual = ">="
+	Less           = "<"
+	LessOrEqual    = "<="
+)
+
+type Filter struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// ListOptions are the query parameters of the list endpoints. Zero values are left out.
+type ListOptions struct {
+	Filters []Filter
+
+	// Extra query parameters, e.g. the date ranges "created_after" or "committed_before".
+	Params url.Values
+
+	// Columns to sort by, "-" prefix sorts descending.
+	Sort   []string
+	Fields []string
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+type Page[T any] struct {
+	Data  []T         `json:"data"`
+	Meta  query.Meta  `json:"meta"`
+	Links query.Links `json:"links"`
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+
+	for key, vs := range o.Params {
+		v[key] = append(v[key], vs...)
+	}
+
+	for _, f := range o.Filters {
+		key, value := f.param()
+
+		v.Add(key, value)
+	}
+
+	if len(o.Sort) > 0 {
+		v.Set("sort", strings.Join(o.Sort, ","))
+	}
+
+	if len(o.Fields) > 0 {
+		v.Set("fields", strings.Join(o.Fields, ","))
+	}
+
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	if o.Offset > 0 {
+		v.Set("offset", strconv.Itoa(o.Offset))
+	}
+
+	if o.Cursor != "" {
+		v.Set("cursor", o.Cursor)
+	}
+
+	return v
+}
+
+// Encodes the filter, so the server parses the same operator: "stargazer_count>=100" is sent as
+// key "stargazer_count>" and value "100", and "stargazer_count>100" as a key without a value.
+func (f Filter) param() (string, string) {
+	switch f.Operator {
+	case GreaterOrEqual, LessOrEqual, NotEqual:
+		return f.Column + f.Operator[:1], f.Value
+	case Greater, Less:
+		return f.Column + f.Operator + f.Value, ""
+	}
+
+	return f.Column, f.Value
+}
+
+func list[T any](ctx context.Context, c *Client, path string, o ListOptions) (*Page[T], error) {
+	page := new(Page[T])
+
+	if err := c.doJson(ctx, http.MethodGet, path, o.values(), nil, page); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Iterator walks through every row of a list endpoint, fetching the pages with the cursor.
+//
+//	it := c.Repositories(ctx, client.ListOptions{})
+//	for it.Next() {
+//		r := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	ctx     context.Context
+	client  *Client
+	path    string
+	options ListOptions
+
+	page  []T
+	index int
+	done  bool
+	err   error
+}
+
+func newIterator[T any](ctx context.Context, c *Client, path string, o ListOptions) *Iterator[T] {
+	// First page starts from the offset or the cursor of the options, the next pages from the cursor.
+	return &Iterator[T]{ctx: ctx, client: c, path: path, options: o, index: -1}
+}
+
+// Next advances to the next row, and fetches the next page when needed. Returns false, when there
+// are no more rows or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.index++
+
+	for it.index >= len(it.page) {
+		if it.done {
+			return false
+		}
+
+		page, err := list[T](it.ctx, it.client, it.path, it.options)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = page.Data
+		it.index = 0
+
+		if page.Meta.NextCursor == "" {
+			it.done = true
+		}
+
+		it.options.Cursor = page.Meta.NextCursor
+		it.options.Offset = 0
+	}
+
+	return true
+}
+
+// Value returns the current row.
+func (it *Iterator[T]) Value() T {
+	return it.page[it.index]
+}
+
+// Err returns the error, which stopped the iteration.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
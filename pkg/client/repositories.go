@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+const repositoryPath = "/repository"
+
+func (c *Client) ListRepositories(ctx context.Context, o ListOptions) (*Page[models.Repository], error) {
+	return list[models.Repository](ctx, c, repositoryPath, o)
+}
+
+// Repositories iterates every repository matching the options.
+func (c *Client) Repositories(ctx context.Context, o ListOptions) *Iterator[models.Repository] {
+	return newIterator[models.Repository](ctx, c, repositoryPath, o)
+}
+
+func (c *Client) GetRepository(ctx context.Context, id int) (*models.Repository, error) {
+	var res struct {
+		Data models.Repository `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodGet, repositoryPath+"/"+strconv.Itoa(id), nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+func (c *Client) CreateRepository(ctx context.Context, i models.CreateRepositoryInput) (*models.Repository, error) {
+	var res struct {
+		Data models.Repository `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPost, repositoryPath, nil, i, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// UpdateRepository updates the non-empty fields of the input.
+func (c *Client) UpdateRepository(ctx context.Context, id int, i models.UpdateRepositoryInput) (*models.Repository, error) {
+	var res struct {
+		Data models.Repository `json:"data"`
+	}
+
+	if err := c.doJson(ctx, http.MethodPatch, repositoryPath+"/"+strconv.Itoa(id), nil, i, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+func (c *Client) DeleteRepository(ctx context.Context, id int) error {
+	return c.doJson(ctx, http.MethodDelete, repositoryPath+"/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// BulkCreateRepositories creates the repositories in a single transaction. If any of the items is
+// invalid or a conflict, nothing is created, and the error is returned with the results of every item.
+func (c *Client) BulkCreateRepositories(ctx context.Context, items []models.CreateRepositoryInput) ([]bulk.Result, error) {
+	return bulkWrite(ctx, c, http.MethodPost, repositoryPath+"/bulk", items)
+}
+
+// BulkUpdateRepositories updates the fields present in the items. Every item requires "id".
+func (c *Client) BulkUpdateRepositories(ctx context.Context, items []map[string]interface{}) ([]bulk.Result, error) {
+	return bulkWrite(ctx, c, http.MethodPatch, repositoryPath+"/bulk", items)
+}
+
+// BulkDeleteRepositories deletes the repositories matching the filters. Deleting every repository
+// requires all to be true.
+func (c *Client) BulkDeleteRepositories(ctx context.Context, filters []Filter, all bool) (int64, error) {
+	return bulkDelete(ctx, c, repositoryPath+"/bulk", filters, all)
+}
+
+// FetchRepositories searches count repositories, and collects their metadata with the plugin of the
+// type, e.g. "go". Returns when the collection is done.
+func (c *Client) FetchRepositories(ctx context.Context, count int, pluginType string) error {
+	q := ListOptions{}.values()
+	q.Set("count", strconv.Itoa(count))
+	q.Set("type", pluginType)
+
+	res, err := c.do(ctx, request{Method: http.MethodGet, Path: repositoryPath + "/fetch", Query: q})
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Configuration of Glass. Values are read once at startup, from the ".env" -file, the environment
+// and the command-line, in the order of precedence: command-line, environment, file. Keys are
+// the names of the environment variables.
+
+const DefaultFile = ".env"
+
+const (
+	KeyDatabaseUser            = "POSTGRES_USER"
+	KeyDatabasePassword        = "POSTGRES_PASSWORD"
+	KeyDatabaseName            = "POSTGRES_DB"
+	KeyDatabaseHost            = "POSTGRES_HOST"
+	KeyDatabasePort            = "POSTGRES_PORT"
+	KeyGitHubUsername          = "GITHUB_USERNAME"
+	KeyGitHubApiToken          = "GITHUB_API_TOKEN"
+	KeyGitHubGraphQlApiBaseUrl = "GITHUB_GRAPHQL_API_BASEURL"
+	KeyGitHubQueryBatchSize    = "GITHUB_QUERY_BATCH_SIZE"
+	KeyGitHubQueryMaxCost      = "GITHUB_QUERY_MAX_COST"
+	KeySourceGraphApiBaseUrl   = "SOURCEGRAPH_GRAPHQL_API_BASEURL"
+	KeySourceGraphPageSize     = "SOURCEGRAPH_QUERY_PAGE_SIZE"
+	KeyBaseUrl                 = "BASEURL"
+	KeyGoPath                  = "GOPATH"
+	KeyTempGoPath              = "TEMP_GOPATH"
+	KeyLocalEnv                = "LOCAL_ENV"
+	KeyLogFormat               = "LOG_FORMAT"
+	KeyLogLevel                = "LOG_LEVEL"
+	KeyDatasetMetricsInterval  = "DATASET_METRICS_INTERVAL"
+)
+
+// Every key except the stage settings, in the order they are documented.
+var Keys = []string{
+	KeyDatabaseUser,
+	KeyDatabasePassword,
+	KeyDatabaseName,
+	KeyDatabaseHost,
+	KeyDatabasePort,
+	KeyGitHubUsername,
+	KeyGitHubApiToken,
+	KeyGitHubGraphQlApiBaseUrl,
+	KeyGitHubQueryBatchSize,
+	KeyGitHubQueryMaxCost,
+	KeySourceGraphApiBaseUrl,
+	KeySourceGraphPageSize,
+	KeyBaseUrl,
+	KeyGoPath,
+	KeyTempGoPath,
+	KeyLocalEnv,
+	KeyLogFormat,
+	KeyLogLevel,
+	KeyDatasetMetricsInterval,
+}
+
+type Config struct {
+	Database    Database
+	GitHub      GitHub
+	SourceGraph SourceGraph
+
+	// Address of the API, e.g. "http://localhost:8080".
+	BaseUrl string
+
+	// GOPATH of the environment, and the GOPATH the libraries of the repositories are downloaded to.
+	GoPath     string
+	TempGoPath string
+
+	// "development" keeps the downloaded libraries.
+	LocalEnv string
+
+	// Settings of the stages of the pipeline by the name of the stage, see Stage.
+	Stages map[string]Stage
+
+	Log Log
+
+	Metrics Metrics
+}
+
+type Database struct {
+	User     string
+	Password string
+	Name     string
+	Host     string
+	Port     string
+}
+
+type GitHub struct {
+	Username string
+
+	// Tokens of the API, which GITHUB_API_TOKEN lists separated by commas. Requests are spread
+	// between the tokens by their rate limits.
+	ApiTokens []string
+
+	GraphQlApiBaseUrl string
+
+	// Repositories queried in a single GraphQL request, at most 100. Default is 25.
+	QueryBatchSize int
+
+	// Rate limit points a single GraphQL request may cost, which limits the repositories of a
+	// request further. Default is 1, the cost of a request of a single repository.
+	QueryMaxCost int
+}
+
+type SourceGraph struct {
+	GraphQlApiBaseUrl string
+
+	// Repositories a single search returns, at most 100000. A search, which hits the limit, is
+	// split to narrower searches. Default is 5000.
+	QueryPageSize int
+}
+
+// Formats of the log lines.
+const (
+	LogFormatLogfmt = "logfmt"
+	LogFormatJson   = "json"
+)
+
+type Log struct {
+	// "logfmt" (default) or "json".
+	Format string
+
+	// Lines below the level are left out, "debug", "info" (default), "warn" or "error". Output
+	// of the commands is logged at "debug".
+	Level slog.Level
+}
+
+type Metrics struct {
+	// Interval of calculating the metrics of the dataset from the database, e.g. the Quality
+	// Measure distribution. Zero doesn't calculate them. Default is 5 minutes.
+	DatasetInterval time.Duration
+}
+
+// Requirement is a group of values, which a command or a service needs.
+type Requirement int
+
+const (
+	RequireDatabase Requirement = iota
+	RequireGitHub
+	RequireSourceGraph
+	RequireGoPaths
+)
+
+// Load reads the file, the environment and the overrides, which are "KEY=VALUE" -pairs. A missing
+// file is allowed, when it is the default file, since every value can come from the environment.
+func Load(file string, overrides []string) (*Config, error) {
+	v := viper.New()
+
+	v.SetConfigFile(file)
+	v.SetConfigType("env")
+	v.AutomaticEnv()
+
+	if err := v.ReadInConfig(); err != nil {
+		if !(file == DefaultFile && errors.Is(err, os.ErrNotExist)) {
+			return nil, fmt.Errorf("reading configuration %s: %w", file, err)
+		}
+	}
+
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid configuration override %q, expected KEY=VALUE", o)
+		}
+
+		v.Set(key, value)
+	}
+
+	c := new(Config)
+
+	c.Database = Database{
+		User:     v.GetString(KeyDatabaseUser),
+		Password: v.GetString(KeyDatabasePassword),
+		Name:     v.GetString(KeyDatabaseName),
+		Host:     v.GetString(KeyDatabaseHost),
+		Port:     v.GetString(KeyDatabasePort),
+	}
+	c.GitHub = GitHub{
+		Username:          v.GetString(KeyGitHubUsername),
+		ApiTokens:         splitList(v.GetString(KeyGitHubApiToken)),
+		GraphQlApiBaseUrl: v.GetString(KeyGitHubGraphQlApiBaseUrl),
+		QueryBatchSize:    25,
+		QueryMaxCost:      1,
+	}
+	c.SourceGraph = SourceGraph{
+		GraphQlApiBaseUrl: v.GetString(KeySourceGraphApiBaseUrl),
+		QueryPageSize:     5000,
+	}
+	c.BaseUrl = v.GetString(KeyBaseUrl)
+	c.GoPath = v.GetString(KeyGoPath)
+	c.TempGoPath = v.GetString(KeyTempGoPath)
+	c.LocalEnv = v.GetString(KeyLocalEnv)
+	c.Stages = defaultStages()
+	c.Log = Log{Format: LogFormatLogfmt, Level: slog.LevelInfo}
+	c.Metrics = Metrics{DatasetInterval: 5 * time.Minute}
+
+	var problems []string
+
+	if format := strings.ToLower(v.GetString(KeyLogFormat)); format != "" {
+		if format != LogFormatLogfmt && format != LogFormatJson {
+			problems = append(problems, KeyLogFormat+" must be logfmt or json")
+		}
+
+		c.Log.Format = format
+	}
+
+	if level := v.GetString(KeyLogLevel); level != "" {
+		if err := c.Log.Level.UnmarshalText([]byte(level)); err != nil {
+			problems = append(problems, KeyLogLevel+" must be debug, info, warn or error")
+		}
+	}
+
+	between := func(key string, max int, value *int) {
+		s := v.GetString(key)
+		if s == "" {
+			return
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 || n > max {
+			problems = append(problems, fmt.Sprintf("%s must be an integer between 1 and %d", key, max))
+			return
+		}
+
+		*value = n
+	}
+
+	between(KeyGitHubQueryBatchSize, 100, &c.GitHub.QueryBatchSize)
+	between(KeyGitHubQueryMaxCost, 5000, &c.GitHub.QueryMaxCost)
+	between(KeySourceGraphPageSize, 100000, &c.SourceGraph.QueryPageSize)
+
+	if interval := v.GetString(KeyDatasetMetricsInterval); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d < 0 {
+			problems = append(problems, KeyDatasetMetricsInterval+" must be a duration, e.g. 5m, or 0 to disable")
+		} else {
+			c.Metrics.DatasetInterval = d
+		}
+	}
+
+	for _, key := range stageKeys() {
+		if value := v.GetString(key); value != "" {
+			if err := c.setStage(key, value); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, ", "))
+	}
+
+	return c, nil
+}
+
+// Validate checks, that the values of the requirements are set and well-formed, and returns an
+// error listing every invalid value.
+func (c *Config) Validate(requirements ...Requirement) error {
+	var problems []string
+
+	required := func(key string, value string) {
+		if value == "" {
+			problems = append(problems, key+" is required")
+		}
+	}
+
+	baseUrl := func(key string, value string) {
+		required(key, value)
+
+		if u, err := url.Parse(value); value != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+			problems = append(problems, key+" must be an http(s) URL")
+		}
+	}
+
+	for _, r := range requirements {
+		switch r {
+		case RequireDatabase:
+			required(KeyDatabaseUser, c.Database.User)
+			required(KeyDatabaseName, c.Database.Name)
+			required(KeyDatabaseHost, c.Database.Host)
+			required(KeyDatabasePort, c.Database.Port)
+
+			if _, err := strconv.Atoi(c.Database.Port); c.Database.Port != "" && err != nil {
+				problems = append(problems, KeyDatabasePort+" must be a port number")
+			}
+		case RequireGitHub:
+			required(KeyGitHubApiToken, strings.Join(c.GitHub.ApiTokens, ","))
+			baseUrl(KeyGitHubGraphQlApiBaseUrl, c.GitHub.GraphQlApiBaseUrl)
+		case RequireSourceGraph:
+			baseUrl(KeySourceGraphApiBaseUrl, c.SourceGraph.GraphQlApiBaseUrl)
+		case RequireGoPaths:
+			required(KeyGoPath, c.GoPath)
+			required(KeyTempGoPath, c.TempGoPath)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s (set the values in %s, in the environment, or with -set KEY=VALUE)", strings.Join(problems, ", "), DefaultFile)
+	}
+
+	return nil
+}
+
+// Splits the comma separated values, and leaves out the empty ones.
+func splitList(s string) []string {
+	var values []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// Dsn returns the connection string of PostgreSQL.
+func (d Database) Dsn() string {
+	return fmt.Sprintf("host=%v port=%v user=%v dbname=%v password=%v sslmode=disable", d.Host, d.Port, d.User, d.Name, d.Password)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Writes the lines to a configuration file of the test.
+func writeFile(t *testing.T, lines string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "test.env")
+
+	if err := os.WriteFile(file, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	// Empty values of the environment are unset.
+	for _, key := range append(stageKeys(), Keys...) {
+		t.Setenv(key, "")
+	}
+
+	file := writeFile(t, "POSTGRES_HOST=file\nGITHUB_QUERY_BATCH_SIZE=50\nSTAGE_CLONE_WORKERS=2\n")
+
+	tests := []struct {
+		name      string
+		overrides []string
+		check     func(c *Config) bool
+		wantErr   bool
+	}{
+		{"file", nil, func(c *Config) bool { return c.Database.Host == "file" && c.GitHub.QueryBatchSize == 50 }, false},
+		{"default", nil, func(c *Config) bool { return c.SourceGraph.QueryPageSize == 5000 && c.GitHub.QueryMaxCost == 1 }, false},
+		{"override of the file", []string{"POSTGRES_HOST=command-line"}, func(c *Config) bool { return c.Database.Host == "command-line" }, false},
+		{"value with an equals sign", []string{"POSTGRES_PASSWORD=a=b"}, func(c *Config) bool { return c.Database.Password == "a=b" }, false},
+		{"stage setting", []string{"STAGE_ENRICH_TIMEOUT=30s"}, func(c *Config) bool {
+			return c.Stage(StageEnrich).Timeout == 30*time.Second && c.Stage(StageClone).Workers == 2
+		}, false},
+		{"list of tokens", []string{"GITHUB_API_TOKEN=a, ,b"}, func(c *Config) bool { return len(c.GitHub.ApiTokens) == 2 }, false},
+		{"override without a value", []string{"POSTGRES_HOST"}, nil, true},
+		{"integer out of range", []string{"GITHUB_QUERY_BATCH_SIZE=101"}, nil, true},
+		{"invalid log format", []string{"LOG_FORMAT=xml"}, nil, true},
+		{"invalid stage setting", []string{"STAGE_CLONE_WORKERS=0"}, nil, true},
+		{"dataset metrics interval", []string{"DATASET_METRICS_INTERVAL=1m"}, func(c *Config) bool { return c.Metrics.DatasetInterval == time.Minute }, false},
+		{"disabled dataset metrics", []string{"DATASET_METRICS_INTERVAL=0"}, func(c *Config) bool { return c.Metrics.DatasetInterval == 0 }, false},
+		{"negative dataset metrics interval", []string{"DATASET_METRICS_INTERVAL=-1m"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(file, tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load(%v) error = %v, want error %v", tt.overrides, err, tt.wantErr)
+			}
+
+			if err == nil && !tt.check(c) {
+				t.Errorf("Load(%v) = %+v", tt.overrides, c)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.env"), nil); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestOverride(t *testing.T) {
+	c := &Config{Stages: defaultStages()}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]Stage
+		wantErr   bool
+	}{
+		{
+			name:      "no overrides",
+			overrides: nil,
+			want:      defaultStages(),
+		},
+		{
+			name:      "settings",
+			overrides: map[string]string{"STAGE_ENRICH_WORKERS": "5", "STAGE_ENRICH_TIMEOUT": "1m", "STAGE_DOWNLOAD_DISK_BUDGET": "2GB"},
+			want: map[string]Stage{
+				StageEnrich:   {Workers: 5, Timeout: time.Minute, BatchSize: 100},
+				StageDownload: {Workers: 20, Timeout: 10 * time.Minute, BatchSize: 100, DiskBudget: 2 << 30},
+			},
+		},
+		{
+			name:      "case-insensitive key",
+			overrides: map[string]string{"stage_clone_batch_size": " 10 "},
+			want:      map[string]Stage{StageClone: {Workers: 1, Timeout: 10 * time.Minute, BatchSize: 10}},
+		},
+		{"unknown key", map[string]string{"POSTGRES_HOST": "localhost"}, nil, true},
+		{"unknown stage", map[string]string{"STAGE_DEPLOY_WORKERS": "1"}, nil, true},
+		{"negative workers", map[string]string{"STAGE_ENRICH_WORKERS": "-1"}, nil, true},
+		{"timeout without a unit", map[string]string{"STAGE_ENRICH_TIMEOUT": "30"}, nil, true},
+		{"invalid disk budget", map[string]string{"STAGE_CLONE_DISK_BUDGET": "lots"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := c.Override(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Override(%v) error = %v, want error %v", tt.overrides, err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			for name, want := range tt.want {
+				if got := o.Stage(name); got != want {
+					t.Errorf("stage %s = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+
+	// The overrides are of the copy only.
+	if c.Stage(StageEnrich).Workers != 20 {
+		t.Errorf("Override changed the configuration: %+v", c.Stage(StageEnrich))
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"500MB", 500 << 20, false},
+		{"20 gb", 20 << 30, false},
+		{"1KB", 1024, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"1.5GB", 0, true},
+		{"GB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stages of the collection pipeline. Each stage has its own limits, which are configured with
+// the keys STAGE_<STAGE>_<SETTING>, e.g. STAGE_ENRICH_WORKERS=10 or STAGE_DOWNLOAD_DISK_BUDGET=20GB,
+// and can be overridden per job.
+
+const (
+	// Searches the repositories from SourceGraph, and writes them to the database.
+	StageFetch = "fetch"
+	// Queries the metadata of the repositories from GitHub.
+	StageEnrich = "enrich"
+	// Clones the repositories, and calculates their codebase sizes.
+	StageClone = "clone"
+	// Reads the libraries of the repositories from their go.mod files.
+	StageDependencies = "dependencies"
+	// Downloads the libraries of the repositories.
+	StageDownload = "download"
+	// Calculates the codebase sizes of the downloaded libraries.
+	StageCount = "count"
+)
+
+// Every stage, in the order they are run.
+var Stages = []string{StageFetch, StageEnrich, StageClone, StageDependencies, StageDownload, StageCount}
+
+const (
+	SettingWorkers    = "WORKERS"
+	SettingTimeout    = "TIMEOUT"
+	SettingBatchSize  = "BATCH_SIZE"
+	SettingDiskBudget = "DISK_BUDGET"
+)
+
+var settings = []string{SettingWorkers, SettingTimeout, SettingBatchSize, SettingDiskBudget}
+
+type Stage struct {
+	// Amount of repositories or libraries processed concurrently.
+	Workers int
+
+	// Timeout of a single request or command, e.g. a GraphQL request or a git clone.
+	Timeout time.Duration
+
+	// Amount of repositories read from, or written to the database at once.
+	BatchSize int
+
+	// Bytes the working directory of the stage may use. Zero is unlimited. Only the clone and
+	// the download stages write to the disk.
+	DiskBudget int64
+}
+
+// Defaults of the stages. Repositories were cloned one at a time, and every other stage was
+// limited to 20 goroutines and requests to 10 minutes.
+func defaultStages() map[string]Stage {
+	stages := make(map[string]Stage, len(Stages))
+
+	for _, name := range Stages {
+		stages[name] = Stage{Workers: 20, Timeout: 10 * time.Minute, BatchSize: 100}
+	}
+
+	clone := stages[StageClone]
+	clone.Workers = 1
+	stages[StageClone] = clone
+
+	return stages
+}
+
+// StageKey returns the key of a setting of a stage, e.g. STAGE_ENRICH_WORKERS.
+func StageKey(stage string, setting string) string {
+	return "STAGE_" + strings.ToUpper(stage) + "_" + setting
+}
+
+func stageKeys() []string {
+	var keys []string
+
+	for _, stage := range Stages {
+		for _, setting := range settings {
+			keys = append(keys, StageKey(stage, setting))
+		}
+	}
+
+	return keys
+}
+
+// Stage returns the settings of the stage.
+func (c *Config) Stage(name string) Stage {
+	if s, ok := c.Stages[name]; ok {
+		return s
+	}
+
+	return defaultStages()[name]
+}
+
+// Override returns a copy of the configuration with the stage settings of the overrides, which
+// are keys and values, e.g. {"STAGE_ENRICH_WORKERS": "5"}. Only the stage settings can be
+// overridden, the returned error lists every invalid key and value.
+func (c *Config) Override(overrides map[string]string) (*Config, error) {
+	o := *c
+
+	o.Stages = make(map[string]Stage, len(Stages))
+	for _, name := range Stages {
+		o.Stages[name] = c.Stage(name)
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var problems []string
+
+	for _, key := range keys {
+		if err := o.setStage(key, overrides[key]); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, ", "))
+	}
+
+	return &o, nil
+}
+
+// Parses the value of a stage setting to the stage.
+func (c *Config) setStage(key string, value string) error {
+	stage, setting, ok := parseStageKey(key)
+	if !ok {
+		return fmt.Errorf("%s is not a stage setting", key)
+	}
+
+	s := c.Stages[stage]
+	value = strings.TrimSpace(value)
+
+	switch setting {
+	case SettingWorkers, SettingBatchSize:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("%s must be a positive integer", key)
+		}
+
+		if setting == SettingWorkers {
+			s.Workers = n
+		} else {
+			s.BatchSize = n
+		}
+	case SettingTimeout:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%s must be a positive duration, e.g. 30s or 10m", key)
+		}
+
+		s.Timeout = d
+	case SettingDiskBudget:
+		n, err := ParseBytes(value)
+		if err != nil {
+			return fmt.Errorf("%s must be an amount of bytes, e.g. 500MB or 20GB", key)
+		}
+
+		s.DiskBudget = n
+	}
+
+	c.Stages[stage] = s
+
+	return nil
+}
+
+// Returns the stage and the setting of a key, e.g. "enrich" and "WORKERS" of STAGE_ENRICH_WORKERS.
+func parseStageKey(key string) (string, string, bool) {
+	for _, stage := range Stages {
+		for _, setting := range settings {
+			if strings.EqualFold(key, StageKey(stage, setting)) {
+				return stage, setting, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+var byteUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseBytes parses an amount of bytes with an optional unit, e.g. "1024", "500MB" or "20GB".
+// Units are binary, 1KB is 1024 bytes.
+func ParseBytes(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			multiplier = u.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid amount of bytes: %q", s)
+	}
+
+	return n * multiplier, nil
+}
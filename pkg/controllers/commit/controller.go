@@ -35,3 +35,23 @@ func UpdateCommitById(c *gin.Context) {
 	h := NewHandler(c)
 	h.HandleUpdateCommitById()
 }
+
+func BulkCreateCommits(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkCreateCommits()
+}
+
+func BulkUpdateCommits(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkUpdateCommits()
+}
+
+func BulkDeleteCommits(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkDeleteCommits()
+}
+
+func GenerateCsv(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGenerateCsv()
+}
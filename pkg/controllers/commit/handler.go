@@ -4,7 +4,11 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/export"
 	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/query"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +17,12 @@ type Handler struct {
 	DatabaseConnection *gorm.DB
 }
 
+// Columns and filters of the list and CSV endpoints.
+var resource = query.Resource{
+	Model:  models.Commit{},
+	Ranges: map[string]string{"committed": "commit_date"},
+}
+
 func NewHandler(c *gin.Context) *Handler {
 	h := new(Handler)
 
@@ -23,37 +33,46 @@ func NewHandler(c *gin.Context) *Handler {
 }
 
 func (h *Handler) HandleGetCommits() {
-	var c []models.Commit
-
-	h.DatabaseConnection.Find(&c)
-
-	h.Context.JSON(http.StatusOK, gin.H{"data": c})
+	query.List[models.Commit](h.Context, h.DatabaseConnection, resource)
 }
 
 func (h *Handler) HandleGetCommitById() {
 	var c models.Commit
 
 	if err := h.DatabaseConnection.Where("id = ?", h.Context.Param("id")).First(&c).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": c})
 }
 
 func (h *Handler) HandleCreateCommit() {
-	var i models.Commit
+	var i models.CreateCommitInput
 
 	if err := h.Context.ShouldBindJSON(&i); err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	var c models.Commit
+	c := newCommit(i)
+
+	var count int64
 
-	c.RepositoryName = i.RepositoryName
-	c.CommitDate = i.CommitDate
-	c.CommitUser = i.CommitUser
+	if err := h.DatabaseConnection.Model(&models.Commit{}).Where(models.Fields(&c, models.CommitKey)).Count(&count).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	if count > 0 {
+		apierror.Abort(h.Context, apierror.Conflict("commit of %s by %s at %s already exists", c.RepositoryName, c.CommitUser, c.CommitDate))
+		return
+	}
 
-	h.DatabaseConnection.Create(&c)
+	if err := h.DatabaseConnection.Create(&c).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": c})
 }
@@ -62,10 +81,14 @@ func (h *Handler) HandleDeleteCommitById() {
 	var c models.Commit
 
 	if err := h.DatabaseConnection.Where("id = ?", h.Context.Param("id")).First(&c).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	h.DatabaseConnection.Delete(&c)
+	if err := h.DatabaseConnection.Delete(&c).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"succeed": c})
 }
@@ -74,16 +97,59 @@ func (h *Handler) HandleUpdateCommitById() {
 	var c models.Commit
 
 	if err := h.DatabaseConnection.Where("id = ?", h.Context.Param("id")).First(&c).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	var i models.Commit
+	var i models.UpdateCommitInput
 
 	if err := h.Context.ShouldBindJSON(&i); err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	// Only the non-empty fields of the input are updated, the id can't be changed.
+	u := newCommit(models.CreateCommitInput(i))
+
+	if err := h.DatabaseConnection.Model(&c).Updates(u).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	h.DatabaseConnection.Model(&c).Updates(i)
+	if err := h.DatabaseConnection.First(&c, c.Id).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": c})
 }
+
+// Creates the commits of a JSON array or NDJSON stream in a single transaction.
+func (h *Handler) HandleBulkCreateCommits() {
+	bulk.Create(h.Context, h.DatabaseConnection, models.CommitKey, newCommit)
+}
+
+// Updates the commits of a JSON array or NDJSON stream, matched by "id", in a single transaction.
+func (h *Handler) HandleBulkUpdateCommits() {
+	bulk.Update[models.UpdateCommitInput, models.Commit](h.Context, h.DatabaseConnection)
+}
+
+// Deletes the commits matching the filters of the query parameters.
+func (h *Handler) HandleBulkDeleteCommits() {
+	bulk.Delete[models.Commit](h.Context, h.DatabaseConnection, resource)
+}
+
+func newCommit(i models.CreateCommitInput) models.Commit {
+	return models.Commit{RepositoryName: i.RepositoryName, CommitDate: i.CommitDate, CommitUser: i.CommitUser}
+}
+
+// Streams the commits as CSV, filtered with the same query parameters as the list endpoint.
+func (h *Handler) HandleGenerateCsv() {
+	q, err := query.Parse(h.Context, resource)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	export.StreamCsv[models.Commit](h.Context, h.DatabaseConnection.Scopes(q.Filter), "commits.csv")
+}
@@ -0,0 +1,15 @@
+package dataset
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func Export(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleExport()
+}
+
+func Import(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleImport()
+}
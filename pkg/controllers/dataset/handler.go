@@ -0,0 +1,93 @@
+package dataset
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/importer"
+	"gorm.io/gorm"
+)
+
+type Handler struct {
+	Context  *gin.Context
+	Database *gorm.DB
+}
+
+func NewHandler(c *gin.Context) *Handler {
+	h := new(Handler)
+
+	h.Context = c
+	h.Database = c.MustGet("db").(*gorm.DB)
+
+	return h
+}
+
+// Exports the whole dataset as a tarball of data files, data dictionary and manifest.
+// Format is selected with the "format" query parameter: "parquet" (default), "jsonl" or "csv".
+func (h *Handler) HandleExport() {
+	format, err := export.ParseFormat(h.Context.Query("format"))
+	if err != nil {
+		apierror.Abort(h.Context, apierror.BadRequest("%s", err))
+		return
+	}
+
+	bundle, err := export.NewBundle(h.Database, format)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	defer bundle.Remove()
+
+	h.Context.Header("Content-Type", "application/gzip")
+	h.Context.Header("Content-Disposition", "attachment; filename="+bundle.FileName())
+	h.Context.Status(http.StatusOK)
+
+	if err := bundle.Archive(h.Context.Writer); err != nil {
+		h.Context.Error(err)
+	}
+}
+
+// Imports a bundle created by the export endpoint. The bundle is read from the "file" field of a
+// multipart form, or from the request body of any other content type. With "dry_run=true" nothing is written, and the
+// response only reports the inserts, updates and conflicts. "on_conflict" is "skip" (default) or "overwrite".
+func (h *Handler) HandleImport() {
+	options, err := importer.ParseOptions(h.Context.Query("dry_run"), h.Context.Query("on_conflict"))
+	if err != nil {
+		apierror.Abort(h.Context, apierror.BadRequest("%s", err))
+		return
+	}
+
+	var body io.Reader = h.Context.Request.Body
+
+	// Other content types, e.g. the default of "curl --data-binary", are the bundle itself, so
+	// the body isn't parsed as a form.
+	if h.Context.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, err := h.Context.FormFile("file")
+		if err != nil {
+			apierror.Abort(h.Context, apierror.BadRequest("multipart form has no file field: %s", err))
+			return
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			apierror.Abort(h.Context, apierror.BadRequest("%s", err))
+			return
+		}
+
+		defer f.Close()
+
+		body = f
+	}
+
+	report, err := importer.Import(h.Database, body, options)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": report})
+}
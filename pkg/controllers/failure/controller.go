@@ -0,0 +1,25 @@
+package failure
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func GetFailures(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGetFailures()
+}
+
+func GetFailureById(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGetFailureById()
+}
+
+func RetryFailureById(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleRetryFailureById()
+}
+
+func RetryFailures(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleRetryFailures()
+}
@@ -0,0 +1,100 @@
+package failure
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/jobs"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/query"
+	"gorm.io/gorm"
+)
+
+type Handler struct {
+	Context  *gin.Context
+	Database *gorm.DB
+	Queue    *jobs.Queue
+}
+
+// Columns and filters of the list endpoint.
+var resource = query.Resource{
+	Model:  models.Failure{},
+	Ranges: map[string]string{"created": "created_at", "updated": "updated_at"},
+}
+
+func NewHandler(c *gin.Context) *Handler {
+	h := new(Handler)
+
+	h.Context = c
+	h.Database = c.MustGet("db").(*gorm.DB)
+	h.Queue = c.MustGet("jobs").(*jobs.Queue)
+
+	return h
+}
+
+func (h *Handler) HandleGetFailures() {
+	query.List[models.Failure](h.Context, h.Database, resource)
+}
+
+func (h *Handler) HandleGetFailureById() {
+	var f models.Failure
+
+	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&f).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": f})
+}
+
+// Queues a job, which runs the stage of the failure again for its repository. The failure is
+// deleted, when the stage succeeds.
+func (h *Handler) HandleRetryFailureById() {
+	var f models.Failure
+
+	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&f).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	j, err := h.Queue.EnqueueRetry(f.Plugin, f.Stage, []string{f.RepositoryName})
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": j})
+}
+
+// Queues a job for every plugin and stage of the failures, which match the filters of the
+// query, e.g. ?class=timeout. Without filters, every failure is retried.
+func (h *Handler) HandleRetryFailures() {
+	q, err := query.Parse(h.Context, resource)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	var fs []models.Failure
+
+	if err := h.Database.Scopes(q.Filter).Order("id").Find(&fs).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	js := []*models.Job{}
+
+	for _, g := range failures.Groups(fs) {
+		j, err := h.Queue.EnqueueRetry(g.Plugin, g.Stage, g.Repositories)
+		if err != nil {
+			apierror.Abort(h.Context, err)
+			return
+		}
+
+		js = append(js, j)
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": js})
+}
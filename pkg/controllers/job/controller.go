@@ -0,0 +1,25 @@
+package job
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func GetJobs(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGetJobs()
+}
+
+func GetJobById(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGetJobById()
+}
+
+func CreateJob(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleCreateJob()
+}
+
+func GetJobLogs(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGetJobLogs()
+}
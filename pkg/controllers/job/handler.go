@@ -0,0 +1,86 @@
+package job
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/jobs"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/query"
+	"gorm.io/gorm"
+)
+
+type Handler struct {
+	Context  *gin.Context
+	Database *gorm.DB
+	Queue    *jobs.Queue
+}
+
+// Columns and filters of the list endpoint.
+var resource = query.Resource{
+	Model:  models.Job{},
+	Ranges: map[string]string{"created": "created_at"},
+}
+
+// Columns and filters of the logs of a job.
+var logResource = query.Resource{
+	Model:  models.JobLog{},
+	Ranges: map[string]string{"created": "created_at"},
+}
+
+func NewHandler(c *gin.Context) *Handler {
+	h := new(Handler)
+
+	h.Context = c
+	h.Database = c.MustGet("db").(*gorm.DB)
+	h.Queue = c.MustGet("jobs").(*jobs.Queue)
+
+	return h
+}
+
+func (h *Handler) HandleGetJobs() {
+	query.List[models.Job](h.Context, h.Database, resource)
+}
+
+func (h *Handler) HandleGetJobById() {
+	var j models.Job
+
+	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&j).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": j})
+}
+
+// Lists the output of the commands, which the job ran for the repositories, e.g. ?repository_name=...&stage=clone.
+func (h *Handler) HandleGetJobLogs() {
+	var j models.Job
+
+	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&j).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	query.List[models.JobLog](h.Context, h.Database.Where("job_id = ?", j.Id).Session(&gorm.Session{}), logResource)
+}
+
+// Queues a job, which runs every stage of the plugin. The stage settings of the configuration
+// can be overridden for the job, e.g. {"overrides": {"STAGE_ENRICH_WORKERS": "5"}}.
+func (h *Handler) HandleCreateJob() {
+	var i models.CreateJobInput
+
+	if err := h.Context.ShouldBindJSON(&i); err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	j, err := h.Queue.Enqueue(i)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	h.Context.JSON(http.StatusOK, gin.H{"data": j})
+}
@@ -38,6 +38,26 @@ func UpdateRepositoryById(c *gin.Context) {
 	h.HandleUpdateRepositoryById()
 }
 
+func BulkCreateRepositories(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkCreateRepositories()
+}
+
+func BulkUpdateRepositories(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkUpdateRepositories()
+}
+
+func BulkDeleteRepositories(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleBulkDeleteRepositories()
+}
+
+func GenerateCsv(c *gin.Context) {
+	h := NewHandler(c)
+	h.HandleGenerateCsv()
+}
+
 // TODO
 func FetchRepositories(c *gin.Context) {
 	h := NewHandler(c)
@@ -1,19 +1,32 @@
 package repository
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/export"
 	"github.com/haapjari/glass/pkg/models"
-	"github.com/haapjari/glass/pkg/plugins/goplg"
-	"github.com/haapjari/glass/pkg/utils"
+	"github.com/haapjari/glass/pkg/plugins"
+	"github.com/haapjari/glass/pkg/query"
 	"gorm.io/gorm"
 )
 
 type Handler struct {
 	Context  *gin.Context
 	Database *gorm.DB
+	Config   *config.Config
+}
+
+// Columns and filters of the list and CSV endpoints.
+var resource = query.Resource{
+	Model:   models.Repository{},
+	Numeric: []string{"open_issue_count", "closed_issue_count", "commit_count", "original_codebase_size", "library_codebase_size", "stargazer_count"},
+	Ranges:  map[string]string{"created": "creation_date", "released": "latest_release"},
 }
 
 func NewHandler(c *gin.Context) *Handler {
@@ -21,38 +34,53 @@ func NewHandler(c *gin.Context) *Handler {
 
 	h.Context = c
 	h.Database = c.MustGet("db").(*gorm.DB)
+	h.Config = c.MustGet("config").(*config.Config)
 
 	return h
 }
 
 func (h *Handler) HandleGetRepositories() {
-	var e []models.Repository
-
-	h.Database.Find(&e)
-
-	h.Context.JSON(http.StatusOK, gin.H{"data": e})
+	query.List[models.Repository](h.Context, h.Database, resource)
 }
 
 func (h *Handler) HandleGetRepositoryById() {
 	var e models.Repository
 
 	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&e).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": e})
 }
 
 func (h *Handler) HandleCreateRepository() {
-	var i models.Repository
+	var i models.CreateRepositoryInput
 
 	if err := h.Context.ShouldBindJSON(&i); err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	r := newRepository(i)
+
+	// Names aren't unique after enrich, so the repositories are matched by their URLs, see models.RepositoryKey.
+	var count int64
+
+	if err := h.Database.Model(&models.Repository{}).Where(models.Fields(&r, models.RepositoryKey)).Count(&count).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	r := models.Repository{LatestRelease: i.LatestRelease, RepositoryName: i.RepositoryName, RepositoryUrl: i.RepositoryUrl, CommitCount: i.CommitCount, OpenIssueCount: i.OpenIssueCount, ClosedIssueCount: i.ClosedIssueCount, OriginalCodebaseSize: i.OriginalCodebaseSize, LibraryCodebaseSize: i.LibraryCodebaseSize, RepositoryType: i.RepositoryType, PrimaryLanguage: i.PrimaryLanguage, CreationDate: i.CreationDate, StargazerCount: i.StargazerCount, LicenseInfo: i.LicenseInfo}
+	if count > 0 {
+		apierror.Abort(h.Context, apierror.Conflict("repository %s already exists", r.RepositoryUrl))
+		return
+	}
 
-	h.Database.Create(&r)
+	if err := h.Database.Create(&r).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": r})
 }
@@ -61,10 +89,14 @@ func (h *Handler) HandleDeleteRepositoryById() {
 	var r models.Repository
 
 	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&r).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	h.Database.Delete(&r)
+	if err := h.Database.Delete(&r).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"succeed": r})
 }
@@ -73,27 +105,82 @@ func (h *Handler) HandleUpdateRepositoryById() {
 	var r models.Repository
 
 	if err := h.Database.Where("id = ?", h.Context.Param("id")).First(&r).Error; err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	var i models.Repository
+	var i models.UpdateRepositoryInput
 
 	if err := h.Context.ShouldBindJSON(&i); err != nil {
-		h.Context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(h.Context, err)
+		return
 	}
 
-	h.Database.Model(&r).Updates(i)
+	// Only the non-empty fields of the input are updated, the id can't be changed.
+	u := newRepository(models.CreateRepositoryInput(i))
+
+	if err := h.Database.Model(&r).Updates(u).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	if err := h.Database.First(&r, r.Id).Error; err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
 	h.Context.JSON(http.StatusOK, gin.H{"data": r})
 }
 
+// Creates the repositories of a JSON array or NDJSON stream in a single transaction.
+func (h *Handler) HandleBulkCreateRepositories() {
+	bulk.Create(h.Context, h.Database, models.RepositoryKey, newRepository)
+}
+
+// Updates the repositories of a JSON array or NDJSON stream, matched by "id", in a single transaction.
+func (h *Handler) HandleBulkUpdateRepositories() {
+	bulk.Update[models.UpdateRepositoryInput, models.Repository](h.Context, h.Database)
+}
+
+// Deletes the repositories matching the filters of the query parameters.
+func (h *Handler) HandleBulkDeleteRepositories() {
+	bulk.Delete[models.Repository](h.Context, h.Database, resource)
+}
+
+func newRepository(i models.CreateRepositoryInput) models.Repository {
+	return models.Repository{LatestRelease: i.LatestRelease, RepositoryName: i.RepositoryName, RepositoryUrl: i.RepositoryUrl, CommitCount: i.CommitCount, OpenIssueCount: i.OpenIssueCount, ClosedIssueCount: i.ClosedIssueCount, OriginalCodebaseSize: i.OriginalCodebaseSize, LibraryCodebaseSize: i.LibraryCodebaseSize, RepositoryType: i.RepositoryType, PrimaryLanguage: i.PrimaryLanguage, CreationDate: i.CreationDate, StargazerCount: i.StargazerCount, LicenseInfo: i.LicenseInfo, DiscoverySource: i.DiscoverySource}
+}
+
+// Streams the repositories as CSV, filtered with the same query parameters as the list endpoint.
+func (h *Handler) HandleGenerateCsv() {
+	q, err := query.Parse(h.Context, resource)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
+
+	export.StreamCsv[models.Repository](h.Context, h.Database.Scopes(q.Filter), "repositories.csv")
+}
+
 func (h *Handler) FetchRepositoryMetadata() {
-	var count, err = strconv.Atoi(h.Context.Query("count"))
-	utils.CheckErr(err)
+	count, err := strconv.Atoi(h.Context.Query("count"))
+	if err != nil || count < 1 {
+		apierror.Abort(h.Context, apierror.Validation([]apierror.FieldError{{Field: "count", Code: "count", Message: "must be a positive integer"}}))
+		return
+	}
+
+	plugin, err := plugins.New(h.Context.Query("type"), h.Database, h.Config, 0)
+	if errors.Is(err, plugins.ErrUnsupported) {
+		apierror.Abort(h.Context, apierror.BadRequest("%s", err))
+		return
+	}
 
-	if h.Context.Query("type") == "go" {
-		plugin := goplg.NewGoPlugin(h.Database)
+	if err != nil {
+		apierror.Abort(h.Context, err)
+		return
+	}
 
-		plugin.GetRepositoryMetadata(count)
+	if err := plugins.Run(plugin, count, nil); err != nil {
+		apierror.Abort(h.Context, err)
 	}
 }
@@ -3,35 +3,49 @@ package database
 import (
 	"fmt"
 
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/logging"
 	"github.com/haapjari/glass/pkg/models"
-	"github.com/haapjari/glass/pkg/utils"
 
-	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func SetupDatabase() *gorm.DB {
-	viper.SetConfigFile(".env")
-	viper.ReadInConfig()
+// SetupDatabase connects to the database, and migrates the tables.
+func SetupDatabase(c config.Database) (*gorm.DB, error) {
+	db, err := Connect(c)
+	if err != nil {
+		return nil, err
+	}
 
-	databaseUser := utils.GetDatabaseUser()
-	databasePassword := utils.GetDatabasePassword()
-	databaseName := utils.GetDatabaseName()
-	databaseHost := utils.GetDatabaseHost()
-	databasePort := utils.GetDatabasePort()
+	if err := Migrate(db); err != nil {
+		return nil, fmt.Errorf("migrating the database: %w", err)
+	}
 
-	dsn := fmt.Sprintf("host=%v port=%v user=%v dbname=%v password=%v sslmode=disable", databaseHost, databasePort, databaseUser, databaseName, databasePassword)
+	return db, nil
+}
 
+func Connect(c config.Database) (*gorm.DB, error) {
 	// Open Database with ORM
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(c.Dsn()), &gorm.Config{Logger: logging.Gorm()})
 	if err != nil {
-		panic("Connection to Database Failed!")
+		return nil, fmt.Errorf("connection to database failed: %w", err)
 	}
 
-	// Auto Creates Tables
-	db.AutoMigrate(&models.Repository{})
-	db.AutoMigrate(&models.Commit{})
+	return db, nil
+}
+
+// Migrate creates the tables, and adds the missing columns.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(Models...)
+}
 
-	return db
+// Models, which are stored in the database.
+var Models = []interface{}{
+	&models.Repository{},
+	&models.Commit{},
+	&models.Snapshot{},
+	&models.Job{},
+	&models.JobLog{},
+	&models.Failure{},
 }
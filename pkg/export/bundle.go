@@ -0,0 +1,221 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/version"
+	"gorm.io/gorm"
+)
+
+type Format string
+
+const (
+	FormatCsv     Format = "csv"
+	FormatJsonl   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// Names of the metadata files inside the bundle.
+const (
+	ManifestFileName = "manifest.json"
+	SchemaFileName   = "schema.json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatCsv, FormatJsonl, FormatParquet:
+		return Format(s), nil
+	case "":
+		return FormatParquet, nil
+	}
+
+	return "", fmt.Errorf("unsupported format: %s", s)
+}
+
+// Manifest describes the contents of a bundle, and the versions of Glass and the Quality Measure
+// formula, that produced the data.
+type Manifest struct {
+	GlassVersion          string         `json:"glass_version"`
+	QualityMeasureVersion string         `json:"quality_measure_version"`
+	Format                Format         `json:"format"`
+	CreatedAt             time.Time      `json:"created_at"`
+	Files                 []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name  string `json:"name"`
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+type bundleTable struct {
+	Name  string
+	Write func(w io.Writer, db *gorm.DB, format Format) (int, error)
+}
+
+// Tables included in a bundle, in the order they are written.
+var bundleTables = []bundleTable{
+	{"repositories", writeTable[models.Repository]},
+	{"commits", writeTable[models.Commit]},
+	{"snapshots", writeTable[models.Snapshot]},
+}
+
+func writeTable[T any](w io.Writer, db *gorm.DB, format Format) (int, error) {
+	switch format {
+	case FormatCsv:
+		var model T
+		return WriteCsv[T](w, db, models.Columns(&model))
+	case FormatJsonl:
+		return WriteJsonl[T](w, db)
+	case FormatParquet:
+		return WriteParquet[T](w, db)
+	}
+
+	return 0, fmt.Errorf("unsupported format: %s", format)
+}
+
+// Bundle is a dataset snapshot in a temporary directory. Bundles are written there before they are
+// archived, and extracted there before they are imported.
+type Bundle struct {
+	Manifest *Manifest
+	dir      string
+}
+
+// NewBundle writes the tables of the database in the given format to a temporary directory.
+// Call Remove, when the bundle is no longer needed.
+func NewBundle(db *gorm.DB, format Format) (*Bundle, error) {
+	dir, err := os.MkdirTemp("", "glass-export-")
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(Bundle)
+
+	b.dir = dir
+	b.Manifest = &Manifest{
+		GlassVersion:          version.Version,
+		QualityMeasureVersion: version.QualityMeasureVersion,
+		Format:                format,
+		CreatedAt:             time.Now().UTC(),
+	}
+
+	for _, table := range bundleTables {
+		name := table.Name + "." + string(format)
+
+		rows, err := b.writeFile(name, func(w io.Writer) (int, error) {
+			return table.Write(w, db, format)
+		})
+		if err != nil {
+			b.Remove()
+			return nil, fmt.Errorf("unable to export %s: %w", table.Name, err)
+		}
+
+		b.Manifest.Files = append(b.Manifest.Files, ManifestFile{Name: name, Table: table.Name, Rows: rows})
+	}
+
+	if _, err := b.writeFile(SchemaFileName, writeJson(NewDictionary())); err != nil {
+		b.Remove()
+		return nil, err
+	}
+
+	if _, err := b.writeFile(ManifestFileName, writeJson(b.Manifest)); err != nil {
+		b.Remove()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// FileName returns a descriptive name for the archive.
+func (b *Bundle) FileName() string {
+	return fmt.Sprintf("glass-dataset-%s-%s.tar.gz", b.Manifest.Format, b.Manifest.CreatedAt.Format("20060102T150405Z"))
+}
+
+// Archive writes the bundle as a gzip compressed tarball, metadata files first.
+func (b *Bundle) Archive(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	names := []string{ManifestFileName, SchemaFileName}
+
+	for _, file := range b.Manifest.Files {
+		names = append(names, file.Name)
+	}
+
+	for _, name := range names {
+		if err := b.archiveFile(tw, name); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// Remove deletes the temporary directory of the bundle.
+func (b *Bundle) Remove() error {
+	return os.RemoveAll(b.dir)
+}
+
+func (b *Bundle) writeFile(name string, write func(w io.Writer) (int, error)) (int, error) {
+	file, err := os.Create(filepath.Join(b.dir, name))
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	rows, err := write(file)
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, file.Close()
+}
+
+func (b *Bundle) archiveFile(tw *tar.Writer, name string) error {
+	file, err := os.Open(filepath.Join(b.dir, name))
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, file)
+
+	return err
+}
+
+func writeJson(v interface{}) func(w io.Writer) (int, error) {
+	return func(w io.Writer) (int, error) {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return 0, encoder.Encode(v)
+	}
+}
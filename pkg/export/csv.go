@@ -0,0 +1,109 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Amount of rows written, before the output is flushed to the client.
+const flushInterval = 500
+
+// SelectColumns parses a comma-separated list of columns, and returns them in the order of the
+// available columns, which keeps the header order stable regardless of the order in the request.
+// Empty selection returns all the available columns.
+func SelectColumns(available []string, selection string) ([]string, error) {
+	if strings.TrimSpace(selection) == "" {
+		return available, nil
+	}
+
+	requested := make(map[string]bool)
+
+	for _, column := range strings.Split(selection, ",") {
+		requested[strings.TrimSpace(column)] = true
+	}
+
+	var columns []string
+
+	for _, column := range available {
+		if requested[column] {
+			columns = append(columns, column)
+			delete(requested, column)
+		}
+	}
+
+	for column := range requested {
+		return nil, fmt.Errorf("unknown column: %s", column)
+	}
+
+	return columns, nil
+}
+
+// WriteCsv writes the rows of the query as RFC 4180 CSV, with a header row of the columns.
+// If the writer is a http.Flusher, the output is flushed periodically. Returns the amount of rows.
+func WriteCsv[T any](w io.Writer, db *gorm.DB, columns []string) (int, error) {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true // RFC 4180 terminates the lines with CRLF.
+
+	if err := cw.Write(columns); err != nil {
+		return 0, err
+	}
+
+	flush := func() {
+		cw.Flush()
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	written := 0
+
+	count, err := forEachRow(db, func(row *T) error {
+		if err := cw.Write(models.Values(row, columns)); err != nil {
+			return err
+		}
+
+		written++
+
+		if written%flushInterval == 0 {
+			flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	flush()
+
+	return count, cw.Error()
+}
+
+// StreamCsv writes the rows of the query as CSV to the response. The "columns" query parameter
+// selects the columns.
+func StreamCsv[T any](c *gin.Context, db *gorm.DB, fileName string) {
+	var model T
+
+	columns, err := SelectColumns(models.Columns(&model), c.Query("columns"))
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("%s", err))
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Status(http.StatusOK)
+
+	if _, err := WriteCsv[T](c.Writer, db, columns); err != nil {
+		c.Error(err)
+	}
+}
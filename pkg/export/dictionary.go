@@ -0,0 +1,129 @@
+package export
+
+import (
+	"reflect"
+
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/version"
+)
+
+// Sources, where the values of the columns originate from.
+const (
+	SourceGlass         = "glass"
+	SourceSourceGraph   = "sourcegraph_graphql"
+	SourceGitHubGraphQl = "github_graphql"
+	SourceGocloc        = "gocloc"
+	SourceGit           = "git"
+)
+
+type Dictionary struct {
+	GlassVersion          string            `json:"glass_version"`
+	QualityMeasureVersion string            `json:"quality_measure_version"`
+	Tables                []DictionaryTable `json:"tables"`
+}
+
+type DictionaryTable struct {
+	Name    string             `json:"name"`
+	Columns []DictionaryColumn `json:"columns"`
+}
+
+type DictionaryColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+}
+
+type columnDescription struct {
+	Source      string
+	Description string
+}
+
+var repositoryColumns = map[string]columnDescription{
+	"id":                     {SourceGlass, "Primary key of the row in the Glass database."},
+	"repository_name":        {SourceSourceGraph, "Name of the repository, as returned by the SourceGraph or GitHub search, or read from the seed list, e.g. \"github.com/owner/name\"."},
+	"repository_url":         {SourceSourceGraph, "Location of the repository, without the scheme, e.g. \"github.com/owner/name\"."},
+	"open_issue_count":       {SourceGitHubGraphQl, "Total count of open issues. Integer stored as a string."},
+	"closed_issue_count":     {SourceGitHubGraphQl, "Total count of closed issues. Integer stored as a string."},
+	"commit_count":           {SourceGitHubGraphQl, "Total count of commits in the history of the default branch. Integer stored as a string."},
+	"original_codebase_size": {SourceGocloc, "Lines of code of the repository itself, calculated from a shallow clone. Integer stored as a string."},
+	"library_codebase_size":  {SourceGocloc, "Lines of code of the libraries listed in the go.mod files of the repository. Integer stored as a string."},
+	"repository_type":        {SourceGlass, "Role of the repository in the dataset, e.g. \"primary\"."},
+	"primary_language":       {SourceGitHubGraphQl, "Primary language of the repository, as detected by GitHub."},
+	"creation_date":          {SourceGitHubGraphQl, "Creation date of the repository as an RFC 3339 timestamp."},
+	"stargazer_count":        {SourceGitHubGraphQl, "Amount of stars of the repository. Integer stored as a string."},
+	"license_info":           {SourceGitHubGraphQl, "SPDX -like license key of the repository, e.g. \"mit\"."},
+	"latest_release":         {SourceGitHubGraphQl, "Publish date of the latest release as an RFC 3339 timestamp."},
+	"discovery_source":       {SourceGlass, "Tag of the source, which discovered the repository, e.g. a SourceGraph query, a GitHub search or a seed list. Empty for repositories collected before the sources were tagged."},
+}
+
+var commitColumns = map[string]columnDescription{
+	"id":              {SourceGlass, "Primary key of the row in the Glass database."},
+	"repository_name": {SourceGit, "Name of the repository, which the commit belongs to, e.g. \"github.com/owner/name\"."},
+	"commit_date":     {SourceGit, "Author date of the commit."},
+	"commit_user":     {SourceGit, "Author of the commit."},
+}
+
+var snapshotColumns = map[string]columnDescription{
+	"id":                      {SourceGlass, "Primary key of the row in the Glass database."},
+	"glass_version":           {SourceGlass, "Version of Glass, which exported the imported bundle."},
+	"quality_measure_version": {SourceGlass, "Version of the Quality Measure formula used in the imported bundle."},
+	"format":                  {SourceGlass, "Format of the data files in the imported bundle."},
+	"exported_at":             {SourceGlass, "Creation time of the imported bundle as an RFC 3339 timestamp."},
+	"imported_at":             {SourceGlass, "Time the bundle was imported as an RFC 3339 timestamp."},
+	"repository_count":        {SourceGlass, "Amount of repository rows in the imported bundle."},
+	"commit_count":            {SourceGlass, "Amount of commit rows in the imported bundle."},
+}
+
+// NewDictionary describes the columns of the exported tables and their provenance.
+func NewDictionary() *Dictionary {
+	d := new(Dictionary)
+
+	d.GlassVersion = version.Version
+	d.QualityMeasureVersion = version.QualityMeasureVersion
+	d.Tables = []DictionaryTable{
+		newDictionaryTable("repositories", models.Repository{}, repositoryColumns),
+		newDictionaryTable("commits", models.Commit{}, commitColumns),
+		newDictionaryTable("snapshots", models.Snapshot{}, snapshotColumns),
+	}
+
+	return d
+}
+
+// Columns are read from the model, so a new field shows up in the dictionary even without a description.
+func newDictionaryTable(name string, model interface{}, descriptions map[string]columnDescription) DictionaryTable {
+	t := reflect.TypeOf(model)
+
+	table := DictionaryTable{Name: name}
+
+	for i := 0; i < t.NumField(); i++ {
+		column := models.ColumnName(t.Field(i))
+		if column == "" {
+			continue
+		}
+
+		description := descriptions[column]
+
+		table.Columns = append(table.Columns, DictionaryColumn{
+			Name:        column,
+			Type:        columnType(t.Field(i).Type),
+			Source:      description.Source,
+			Description: description.Description,
+		})
+	}
+
+	return table
+}
+
+func columnType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
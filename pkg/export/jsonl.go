@@ -0,0 +1,18 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// WriteJsonl writes the rows of the query as newline-delimited JSON, one object per line.
+// Returns the amount of rows.
+func WriteJsonl[T any](w io.Writer, db *gorm.DB) (int, error) {
+	encoder := json.NewEncoder(w)
+
+	return forEachRow(db, func(row *T) error {
+		return encoder.Encode(row)
+	})
+}
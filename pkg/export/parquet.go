@@ -0,0 +1,45 @@
+package export
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"gorm.io/gorm"
+)
+
+// WriteParquet writes the rows of the query as an Apache Parquet file. Columns are named with the
+// "parquet" -tags of the model. Returns the amount of rows.
+func WriteParquet[T any](w io.Writer, db *gorm.DB) (int, error) {
+	writer := parquet.NewGenericWriter[T](w)
+
+	buffer := make([]T, 0, flushInterval)
+
+	write := func() error {
+		if _, err := writer.Write(buffer); err != nil {
+			return err
+		}
+
+		buffer = buffer[:0]
+
+		return nil
+	}
+
+	count, err := forEachRow(db, func(row *T) error {
+		buffer = append(buffer, *row)
+
+		if len(buffer) == flushInterval {
+			return write()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := write(); err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
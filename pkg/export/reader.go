@@ -0,0 +1,205 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+// OpenBundle extracts a bundle archive, written by Bundle.Archive, to a temporary directory.
+// Call Remove, when the bundle is no longer needed.
+func OpenBundle(r io.Reader) (*Bundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("bundle is not a gzip compressed tarball: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "glass-import-")
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(Bundle)
+	b.dir = dir
+
+	if err := b.extract(tar.NewReader(gr)); err != nil {
+		b.Remove()
+		return nil, err
+	}
+
+	manifest, err := os.Open(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		b.Remove()
+		return nil, fmt.Errorf("bundle does not contain %s", ManifestFileName)
+	}
+
+	defer manifest.Close()
+
+	if err := json.NewDecoder(manifest).Decode(&b.Manifest); err != nil {
+		b.Remove()
+		return nil, fmt.Errorf("unable to parse %s: %w", ManifestFileName, err)
+	}
+
+	if _, err := ParseFormat(string(b.Manifest.Format)); err != nil {
+		b.Remove()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// File returns the manifest entry of the table, or nil if the bundle does not contain the table.
+func (b *Bundle) File(table string) *ManifestFile {
+	for i := range b.Manifest.Files {
+		if b.Manifest.Files[i].Table == table {
+			return &b.Manifest.Files[i]
+		}
+	}
+
+	return nil
+}
+
+// ReadTable calls fn for every row of the table in the bundle. Missing table is not an error,
+// which keeps the bundles of the older versions importable.
+func ReadTable[T any](b *Bundle, table string, fn func(row *T) error) error {
+	entry := b.File(table)
+	if entry == nil {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Join(b.dir, filepath.Base(entry.Name)))
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	switch b.Manifest.Format {
+	case FormatCsv:
+		return readCsv(file, fn)
+	case FormatJsonl:
+		return readJsonl(file, fn)
+	case FormatParquet:
+		return readParquet(file, fn)
+	}
+
+	return fmt.Errorf("unsupported format: %s", b.Manifest.Format)
+}
+
+// Extract the regular files of the archive. Directories of the entries are dropped, so the
+// entries can't be written outside of the temporary directory.
+func (b *Bundle) extract(tr *tar.Reader) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to read bundle: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		file, err := os.Create(filepath.Join(b.dir, filepath.Base(header.Name)))
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(file, tr); err != nil {
+			file.Close()
+			return err
+		}
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+func readCsv[T any](r io.Reader, fn func(row *T) error) error {
+	cr := csv.NewReader(r)
+
+	columns, err := cr.Read()
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var row T
+
+		if err := models.SetValues(&row, columns, record); err != nil {
+			return err
+		}
+
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+}
+
+func readJsonl[T any](r io.Reader, fn func(row *T) error) error {
+	decoder := json.NewDecoder(r)
+
+	for {
+		var row T
+
+		err := decoder.Decode(&row)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+}
+
+func readParquet[T any](file *os.File, fn func(row *T) error) error {
+	reader := parquet.NewGenericReader[T](file)
+	defer reader.Close()
+
+	buffer := make([]T, flushInterval)
+
+	for {
+		n, err := reader.Read(buffer)
+
+		for i := 0; i < n; i++ {
+			if err := fn(&buffer[i]); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package export
+
+import (
+	"gorm.io/gorm"
+)
+
+// Iterates the rows of the query one at a time, without reading the whole table to memory.
+// Returns the amount of rows iterated.
+func forEachRow[T any](db *gorm.DB, fn func(row *T) error) (int, error) {
+	var model T
+
+	rows, err := db.Model(&model).Order("id").Rows()
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	count := 0
+
+	for rows.Next() {
+		var row T
+
+		if err := db.ScanRows(rows, &row); err != nil {
+			return count, err
+		}
+
+		if err := fn(&row); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, rows.Err()
+}
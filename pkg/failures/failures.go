@@ -0,0 +1,235 @@
+package failures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/metrics/prom"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Failures of single repositories in the stages of the pipeline. A failing repository is recorded
+// with the class of the error, and the stage continues with the other repositories. The failure
+// is resolved, when the stage succeeds for the repository, e.g. when it is retried.
+
+const (
+	ClassTimeout    = "timeout"
+	ClassNetwork    = "network"
+	ClassHttp       = "http"
+	ClassGraphQl    = "graphql"
+	ClassParse      = "parse"
+	ClassDatabase   = "database"
+	ClassCommand    = "command"
+	ClassFilesystem = "filesystem"
+	ClassDiskBudget = "disk_budget"
+	ClassUnknown    = "unknown"
+)
+
+// Every class, in the order they are classified.
+var Classes = []string{ClassTimeout, ClassNetwork, ClassHttp, ClassGraphQl, ClassParse, ClassDatabase, ClassCommand, ClassFilesystem, ClassDiskBudget, ClassUnknown}
+
+// Error of a repository in a stage.
+type Error struct {
+	Repository string
+	Stage      string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Stage, e.Repository, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New wraps the error of the repository in the stage. Returns nil, if the error is nil.
+func New(stage string, repository string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Repository: repository, Stage: stage, Err: err}
+}
+
+// StatusError is returned for a response, which doesn't have a successful status.
+type StatusError struct {
+	Url    string
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded %d: %s", e.Url, e.Status, e.Body)
+}
+
+// GraphQlError is returned for a GraphQL response, which has errors.
+type GraphQlError struct {
+	Url      string
+	Messages []string
+}
+
+func (e *GraphQlError) Error() string {
+	return fmt.Sprintf("%s responded with errors: %s", e.Url, strings.Join(e.Messages, "; "))
+}
+
+// ErrDiskBudget is returned for work, which was skipped, because the disk budget of the stage
+// was exceeded.
+var ErrDiskBudget = errors.New("disk budget exceeded")
+
+// Errors of the database, which are classified by their type, because GORM doesn't wrap them.
+type databaseError interface{ SQLState() string }
+
+// Classify returns the class of the error.
+func Classify(err error) string {
+	var (
+		netErr     net.Error
+		statusErr  *StatusError
+		graphErr   *GraphQlError
+		syntaxErr  *json.SyntaxError
+		typeErr    *json.UnmarshalTypeError
+		sqlErr     databaseError
+		exitErr    *exec.ExitError
+		execErr    *exec.Error
+		pathErr    *fs.PathError
+		timeoutErr interface{ Timeout() bool }
+	)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &timeoutErr) && timeoutErr.Timeout():
+		return ClassTimeout
+	case errors.As(err, &netErr):
+		return ClassNetwork
+	case errors.As(err, &statusErr):
+		return ClassHttp
+	case errors.As(err, &graphErr):
+		return ClassGraphQl
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ClassParse
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.As(err, &sqlErr):
+		return ClassDatabase
+	case errors.As(err, &exitErr), errors.As(err, &execErr):
+		return ClassCommand
+	case errors.As(err, &pathErr):
+		return ClassFilesystem
+	case errors.Is(err, ErrDiskBudget):
+		return ClassDiskBudget
+	}
+
+	return ClassUnknown
+}
+
+// Recorder stores the failures of a plugin, and logs them.
+type Recorder struct {
+	Database *gorm.DB
+	Plugin   string
+	Logger   *slog.Logger
+}
+
+func NewRecorder(db *gorm.DB, plugin string, logger *slog.Logger) *Recorder {
+	return &Recorder{Database: db, Plugin: plugin, Logger: logger}
+}
+
+// Record stores the failure of the repository in the stage. A repository, which fails again in
+// the same stage, updates the existing failure, and increments its attempts.
+func (r *Recorder) Record(e *Error) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	f := models.Failure{Plugin: r.Plugin, RepositoryName: e.Repository, Stage: e.Stage}
+
+	err := r.Database.Where(&f).Attrs(models.Failure{CreatedAt: now}).FirstOrInit(&f).Error
+	if err != nil {
+		return err
+	}
+
+	f.Class = Classify(e.Err)
+	f.Message = e.Err.Error()
+	f.Attempts++
+	f.UpdatedAt = now
+
+	return r.Database.Save(&f).Error
+}
+
+// Resolve deletes the failures of the repositories in the stage.
+func (r *Recorder) Resolve(stage string, repositories []string) error {
+	if len(repositories) == 0 {
+		return nil
+	}
+
+	return r.Database.Where("plugin = ? AND stage = ? AND repository_name IN ?", r.Plugin, stage, repositories).Delete(&models.Failure{}).Error
+}
+
+// Collect records the errors, which are failures of repositories, and resolves the repositories,
+// which succeeded. Returns the errors, which are not failures of repositories, joined.
+func (r *Recorder) Collect(stage string, repositories []string, errs []error) error {
+	var (
+		resolved []string
+		others   []error
+	)
+
+	for i, err := range errs {
+		var e *Error
+
+		switch {
+		case err == nil:
+			resolved = append(resolved, repositories[i])
+		case errors.As(err, &e):
+			prom.RepositoryFailures.WithLabelValues(r.Plugin, e.Stage, Classify(e.Err)).Inc()
+
+			r.Logger.Warn("repository failed", logging.KeyStage, e.Stage, logging.KeyRepository, e.Repository, "class", Classify(e.Err), logging.KeyError, e.Err)
+
+			if err := r.Record(e); err != nil {
+				others = append(others, err)
+			}
+		default:
+			others = append(others, err)
+		}
+	}
+
+	if err := r.Resolve(stage, resolved); err != nil {
+		others = append(others, err)
+	}
+
+	r.Logger.Info("batch finished", logging.KeyStage, stage, "repositories", len(repositories), "succeeded", len(resolved))
+
+	return errors.Join(others...)
+}
+
+// Group of failures of a plugin in a stage, which are retried together.
+type Group struct {
+	Plugin       string
+	Stage        string
+	Repositories []string
+}
+
+// Groups groups the failures by their plugin and stage, in the order of the failures.
+func Groups(failures []models.Failure) []Group {
+	var groups []Group
+
+	index := make(map[[2]string]int)
+
+	for _, f := range failures {
+		key := [2]string{f.Plugin, f.Stage}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Plugin: f.Plugin, Stage: f.Stage})
+		}
+
+		groups[i].Repositories = append(groups[i].Repositories, f.RepositoryName)
+	}
+
+	return groups
+}
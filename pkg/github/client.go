@@ -0,0 +1,315 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/graphql"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/metrics/prom"
+)
+
+// Client of the GraphQL API of GitHub, which stays within its rate limits. Every request waits,
+// while the rate limit is exhausted, and the responses of the rate limits, the server errors and
+// the network errors are retried with jittered exponential backoff. Other errors are permanent,
+// and returned at once, e.g. an invalid query. Errors of single fields, e.g. a repository, which
+// doesn't exist, are left to the caller in the response, since the other fields of the query
+// have their data.
+//
+// The rate limit is shared by every request of a token, so a rate limited response pauses every
+// request of the token, until GitHub resets the limit or the Retry-After has passed, see Tokens.
+
+// Reasons of the retries.
+const (
+	ReasonRateLimit          = "rate_limit"
+	ReasonSecondaryRateLimit = "secondary_rate_limit"
+	ReasonServerError        = "server_error"
+	ReasonNetwork            = "network"
+)
+
+// GitHub asks to wait at least a minute after a secondary rate limit without Retry-After.
+const secondaryRateLimitWait = time.Minute
+
+type Client struct {
+	HttpClient *http.Client
+	Url        string
+	Logger     *slog.Logger
+
+	// Timeout of a single request. Waiting for the rate limit and the backoff doesn't count.
+	Timeout time.Duration
+
+	// Retries after the first request, and the bounds of the backoff between them.
+	MaxRetries int
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+
+	Tokens *Tokens
+}
+
+// NewClient returns a client of the GraphQL API at the URL, which authenticates the requests with
+// the tokens.
+func NewClient(httpClient *http.Client, url string, tokens []string, logger *slog.Logger) *Client {
+	c := new(Client)
+
+	c.HttpClient = httpClient
+	c.Url = url
+	c.Logger = logger
+	c.Tokens = NewTokens(tokens)
+	c.MaxRetries = 5
+	c.MinDelay = time.Second
+	c.MaxDelay = 2 * time.Minute
+
+	return c
+}
+
+// A response, which should be retried, and the time to wait before it. Zero wait is the backoff
+// of the attempt. Shared waits pause every request of the token.
+type retry struct {
+	reason string
+	wait   time.Duration
+	shared bool
+}
+
+// Do sends the request, and returns the response. Errors of single fields are left in the
+// response, see graphql.Response.Field.
+func (c *Client) Do(ctx context.Context, request graphql.Request) (*graphql.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		tok, err := c.Tokens.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		response, r, err := c.send(ctx, tok, body)
+		if r == nil {
+			return response, err
+		}
+
+		if attempt >= c.MaxRetries {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		prom.GitHubRetries.WithLabelValues(r.reason).Inc()
+
+		if r.shared {
+			c.pause(tok, r.reason, r.wait, err)
+			continue
+		}
+
+		wait := r.wait
+		if wait <= 0 {
+			wait = c.backoff(attempt)
+		}
+
+		c.Logger.Info("retrying the request to GitHub", "reason", r.reason, "attempt", attempt+1, "wait", wait, logging.KeyError, err)
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Sends the request once. Returns the retry, if the request should be retried.
+func (c *Client) send(ctx context.Context, tok *token, body []byte) (*graphql.Response, *retry, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", c.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/vnd.github.v3+json")
+	request.Header.Set("Authorization", "bearer "+tok.value)
+
+	start := time.Now()
+
+	res, err := c.HttpClient.Do(request)
+
+	prom.ObserveGraphQl(prom.ApiGitHub, res, err, time.Since(start))
+
+	if err != nil {
+		// Timeouts and cancellations are not retried, the request may be too heavy to ever finish.
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, err
+		}
+
+		return nil, &retry{reason: ReasonNetwork}, err
+	}
+
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, &retry{reason: ReasonNetwork}, err
+	}
+
+	remaining, reset := rateLimitHeaders(res.Header)
+	c.Tokens.update(tok, remaining, reset)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err := &failures.StatusError{Url: c.Url, Status: res.StatusCode, Body: strings.TrimSpace(string(data))}
+
+		return nil, statusRetry(res, remaining, reset, string(data)), err
+	}
+
+	response, err := graphql.Decode(c.Url, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := response.Err(); err != nil {
+		return nil, rateLimitedRetry(response, reset), err
+	}
+
+	// Rate limit of the query, when it asks for one, is more accurate than the headers.
+	var limit struct {
+		RateLimit *RateLimit `json:"rateLimit"`
+	}
+
+	if err := response.Decode(&limit); err == nil && limit.RateLimit != nil {
+		c.Logger.Debug("queried GitHub", "token", tok.label, "cost", limit.RateLimit.Cost, "remaining", limit.RateLimit.Remaining)
+
+		remaining = limit.RateLimit.Remaining
+		if t, err := time.Parse(time.RFC3339, limit.RateLimit.ResetAt); err == nil {
+			reset = t
+		}
+
+		c.Tokens.update(tok, remaining, reset)
+	}
+
+	// The request succeeded with the last of the budget, so the next ones of the token wait for
+	// the reset.
+	if remaining == 0 && !reset.IsZero() {
+		c.pause(tok, ReasonRateLimit, time.Until(reset), nil)
+	}
+
+	return response, nil, nil
+}
+
+// Returns the retry of the unsuccessful response, or nil, if the error is permanent.
+func statusRetry(res *http.Response, remaining int, reset time.Time, body string) *retry {
+	switch {
+	case res.StatusCode >= 500:
+		return &retry{reason: ReasonServerError}
+	case res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests:
+		return nil
+	}
+
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		return &retry{reason: ReasonSecondaryRateLimit, wait: time.Duration(seconds) * time.Second, shared: true}
+	}
+
+	if remaining == 0 && !reset.IsZero() {
+		return &retry{reason: ReasonRateLimit, wait: time.Until(reset), shared: true}
+	}
+
+	body = strings.ToLower(body)
+	if strings.Contains(body, "secondary rate limit") || strings.Contains(body, "abuse") {
+		return &retry{reason: ReasonSecondaryRateLimit, wait: secondaryRateLimitWait, shared: true}
+	}
+
+	return nil
+}
+
+// Fields of the rate limit of a query, which GitHub returns, when the query asks for
+// rateLimit { cost remaining resetAt }.
+type RateLimit struct {
+	Cost      int    `json:"cost"`
+	Remaining int    `json:"remaining"`
+	ResetAt   string `json:"resetAt"`
+}
+
+// Returns the retry of the response, which has errors, if it was rate limited. Rate limited
+// responses are retried at the reset, other errors are permanent.
+func rateLimitedRetry(response *graphql.Response, reset time.Time) *retry {
+	for _, e := range response.Errors {
+		if e.Type != "RATE_LIMITED" {
+			continue
+		}
+
+		wait := secondaryRateLimitWait
+		if !reset.IsZero() {
+			wait = time.Until(reset)
+		}
+
+		return &retry{reason: ReasonRateLimit, wait: wait, shared: true}
+	}
+
+	return nil
+}
+
+// Reads the remaining requests and the reset of the rate limit from the headers. Remaining is
+// -1, when the headers are missing.
+func rateLimitHeaders(h http.Header) (int, time.Time) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		remaining = -1
+	}
+
+	var reset time.Time
+
+	if seconds, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(seconds, 0)
+	}
+
+	return remaining, reset
+}
+
+// Pauses every request of the token for the duration. A longer pause isn't shortened.
+func (c *Client) pause(tok *token, reason string, wait time.Duration, err error) {
+	if wait <= 0 {
+		return
+	}
+
+	until := time.Now().Add(wait)
+
+	if c.Tokens.pause(tok, until) {
+		c.Logger.Warn("pausing the requests of a GitHub token", "token", tok.label, "reason", reason, "until", until.UTC().Format(time.RFC3339), logging.KeyError, err)
+	}
+}
+
+// Backoff of the attempt, which doubles from MinDelay up to MaxDelay, with a random half of it
+// as jitter, so the concurrent requests don't retry at once.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.MinDelay << attempt
+	if d > c.MaxDelay || d <= 0 {
+		d = c.MaxDelay
+	}
+
+	half := d / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/graphql"
+)
+
+// Response of the fake GitHub, by the number of the request, from 0.
+type response func(w http.ResponseWriter, n int)
+
+// Returns a client of a fake GitHub, which responds with the responses in order, and the last one
+// after them, and the tokens of the requests it received.
+func newClient(t *testing.T, tokens []string, responses ...response) (*Client, func() []string) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(received)
+		received = append(received, strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "))
+		mu.Unlock()
+
+		responses[min(n, len(responses)-1)](w, n)
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.Client(), server.URL, tokens, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.MinDelay = time.Millisecond
+	c.MaxDelay = 10 * time.Millisecond
+
+	return c, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), received...)
+	}
+}
+
+func ok(w http.ResponseWriter, n int) {
+	w.Write([]byte(`{"data": {"viewer": {"login": "glass"}}}`))
+}
+
+func status(code int, header ...string) response {
+	return func(w http.ResponseWriter, n int) {
+		for i := 0; i+1 < len(header); i += 2 {
+			w.Header().Set(header[i], header[i+1])
+		}
+
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+var request = graphql.Request{Query: "query { viewer { login } }"}
+
+func TestSecondaryRateLimit(t *testing.T) {
+	t.Run("paused token", func(t *testing.T) {
+		c, received := newClient(t, []string{"a"}, status(http.StatusForbidden, "Retry-After", "1"), ok)
+
+		start := time.Now()
+
+		if _, err := c.Do(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d < time.Second {
+			t.Errorf("retried after %v, want the Retry-After of 1s", d)
+		}
+
+		if r := received(); len(r) != 2 {
+			t.Errorf("requests = %v, want 2", r)
+		}
+	})
+
+	t.Run("other token", func(t *testing.T) {
+		c, received := newClient(t, []string{"a", "b"}, status(http.StatusForbidden, "Retry-After", "60"), ok)
+
+		start := time.Now()
+
+		if _, err := c.Do(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d > 10*time.Second {
+			t.Errorf("retried after %v, want at once with the other token", d)
+		}
+
+		if r := received(); len(r) != 2 || r[0] == r[1] {
+			t.Errorf("tokens of the requests = %v, want both", r)
+		}
+	})
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []response
+		requests  int
+		status    int
+	}{
+		{"server error", []response{status(http.StatusBadGateway), ok}, 2, 0},
+		{"server errors until the last retry", []response{status(500), status(502), status(503), ok}, 4, 0},
+		{"retries run out", []response{status(http.StatusServiceUnavailable)}, 4, http.StatusServiceUnavailable},
+		{"forbidden", []response{status(http.StatusForbidden)}, 1, http.StatusForbidden},
+		{"not found", []response{status(http.StatusNotFound)}, 1, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, received := newClient(t, []string{"a"}, tt.responses...)
+			c.MaxRetries = 3
+
+			r, err := c.Do(context.Background(), request)
+
+			var e *failures.StatusError
+
+			switch {
+			case tt.status == 0 && (err != nil || r == nil):
+				t.Errorf("Do = %v, %v, want a response", r, err)
+			case tt.status != 0 && (!errors.As(err, &e) || e.Status != tt.status):
+				t.Errorf("error = %v, want status %d", err, tt.status)
+			}
+
+			if n := len(received()); n != tt.requests {
+				t.Errorf("requests = %d, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestGivingUp(t *testing.T) {
+	c, _ := newClient(t, []string{"a"}, status(http.StatusBadGateway))
+	c.MaxRetries = 2
+
+	_, err := c.Do(context.Background(), request)
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Errorf("error = %v, want giving up after 3 attempts", err)
+	}
+}
+
+func TestRetriesAreCancelled(t *testing.T) {
+	c, received := newClient(t, []string{"a"}, status(http.StatusBadGateway))
+	c.MinDelay, c.MaxDelay = time.Hour, time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Do(ctx, request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the deadline", err)
+	}
+
+	if n := len(received()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{MinDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if d := c.backoff(tt.attempt); d < tt.max/2 || d > tt.max {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.max/2, tt.max)
+		}
+	}
+}
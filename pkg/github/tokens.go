@@ -0,0 +1,138 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haapjari/glass/pkg/metrics/prom"
+)
+
+// Every token of GitHub has a rate limit of its own. A request uses the token with the most points
+// left, so the requests are spread between the tokens, and a token, which is rate limited, is
+// paused alone, while the others are used.
+
+// Token of the API, and the state of its rate limit by the latest response.
+type token struct {
+	value string
+
+	// Position of the token in the configuration, from 1, which labels its metrics and logs
+	// instead of the secret.
+	label string
+
+	// Points left until the reset, by the latest response, less the requests sent after it.
+	remaining int
+	reset     time.Time
+
+	pausedUntil time.Time
+}
+
+// Points of a token in an hour, until a response tells otherwise.
+const defaultLimit = 5000
+
+// ErrNoTokens is returned for the requests of a pool without tokens.
+var ErrNoTokens = errors.New("no GitHub API tokens")
+
+// Pool of the tokens.
+type Tokens struct {
+	mu     sync.Mutex
+	tokens []*token
+}
+
+func NewTokens(values []string) *Tokens {
+	t := new(Tokens)
+
+	for i, v := range values {
+		t.tokens = append(t.tokens, &token{value: v, label: strconv.Itoa(i + 1), remaining: defaultLimit})
+	}
+
+	return t
+}
+
+// Returns the token with the most points left, and counts a point of it as used, so the
+// concurrent requests take the other tokens. Waits, while every token is paused.
+func (t *Tokens) acquire(ctx context.Context) (*token, error) {
+	if len(t.tokens) == 0 {
+		return nil, ErrNoTokens
+	}
+
+	for {
+		t.mu.Lock()
+
+		now := time.Now()
+
+		var (
+			best *token
+			wait time.Duration
+		)
+
+		for _, tok := range t.tokens {
+			if d := tok.pausedUntil.Sub(now); d > 0 {
+				if wait == 0 || d < wait {
+					wait = d
+				}
+
+				continue
+			}
+
+			// The window was reset after the latest response.
+			if !tok.reset.IsZero() && now.After(tok.reset) {
+				tok.remaining, tok.reset = defaultLimit, time.Time{}
+			}
+
+			if best == nil || tok.remaining > best.remaining {
+				best = tok
+			}
+		}
+
+		if best != nil {
+			best.remaining--
+		}
+
+		t.mu.Unlock()
+
+		if best != nil {
+			prom.GitHubTokenRequests.WithLabelValues(best.label).Inc()
+			return best, nil
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Records the rate limit of the token by a response.
+func (t *Tokens) update(tok *token, remaining int, reset time.Time) {
+	if remaining < 0 {
+		return
+	}
+
+	prom.GitHubRateLimitRemaining.WithLabelValues(tok.label).Set(float64(remaining))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tok.remaining = remaining
+
+	if !reset.IsZero() {
+		tok.reset = reset
+	}
+}
+
+// Pauses the token until the time. Returns false, if the token was already paused about as long,
+// e.g. by the other responses of the same rate limit.
+func (t *Tokens) pause(tok *token, until time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !until.After(tok.pausedUntil.Add(time.Second)) {
+		return false
+	}
+
+	tok.pausedUntil = until
+
+	return true
+}
@@ -0,0 +1,119 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Returns the values of the tokens of n acquires.
+func acquireAll(t *testing.T, tokens *Tokens, n int) []string {
+	t.Helper()
+
+	values := make([]string, n)
+
+	for i := range values {
+		tok, err := tokens.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values[i] = tok.value
+	}
+
+	return values
+}
+
+func TestAcquireRotates(t *testing.T) {
+	tokens := NewTokens([]string{"a", "b"})
+
+	if got, want := acquireAll(t, tokens, 4), []string{"a", "b", "a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("tokens = %v, want %v", got, want)
+	}
+
+	// A token with less points left is used less.
+	tokens.update(tokens.tokens[0], 10, time.Now().Add(time.Hour))
+
+	if got, want := acquireAll(t, tokens, 2), []string{"b", "b"}; !slices.Equal(got, want) {
+		t.Errorf("tokens = %v, want %v", got, want)
+	}
+}
+
+func TestAcquireSkipsPausedTokens(t *testing.T) {
+	tokens := NewTokens([]string{"a", "b"})
+
+	if !tokens.pause(tokens.tokens[0], time.Now().Add(100*time.Millisecond)) {
+		t.Fatal("pause = false, want the token paused")
+	}
+
+	if got, want := acquireAll(t, tokens, 3), []string{"b", "b", "b"}; !slices.Equal(got, want) {
+		t.Errorf("tokens while a is paused = %v, want %v", got, want)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if got, want := acquireAll(t, tokens, 1), []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("tokens after the pause = %v, want %v", got, want)
+	}
+}
+
+func TestAcquireWaitsForPausedTokens(t *testing.T) {
+	tokens := NewTokens([]string{"a", "b"})
+
+	tokens.pause(tokens.tokens[0], time.Now().Add(200*time.Millisecond))
+	tokens.pause(tokens.tokens[1], time.Now().Add(100*time.Millisecond))
+
+	start := time.Now()
+
+	tok, err := tokens.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := time.Since(start); tok.value != "b" || d < 100*time.Millisecond {
+		t.Errorf("acquired %s after %v, want b after its pause of 100ms", tok.value, d)
+	}
+
+	// Cancelled, while every token is paused.
+	tokens.pause(tokens.tokens[1], time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	tokens.pause(tokens.tokens[0], time.Now().Add(time.Hour))
+
+	if _, err := tokens.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the deadline", err)
+	}
+}
+
+func TestPause(t *testing.T) {
+	tokens := NewTokens([]string{"a"})
+	tok := tokens.tokens[0]
+	until := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name  string
+		until time.Time
+		want  bool
+	}{
+		{"pause", until, true},
+		{"same pause", until.Add(time.Millisecond), false},
+		{"shorter pause", until.Add(-time.Second), false},
+		{"longer pause", until.Add(time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		if got := tokens.pause(tok, tt.until); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAcquireWithoutTokens(t *testing.T) {
+	if _, err := NewTokens(nil).acquire(context.Background()); !errors.Is(err, ErrNoTokens) {
+		t.Errorf("error = %v, want ErrNoTokens", err)
+	}
+}
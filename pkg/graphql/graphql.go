@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/metrics/prom"
+)
+
+// Requests and responses of the GraphQL APIs of SourceGraph and GitHub. Values are passed to the
+// queries as variables, instead of writing them in the query documents, so the documents are
+// constants, and the values need no escaping.
+
+// Request of a query document, the operation of it to run, and the variables of the operation.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error of a response. Errors of single fields have the path of the field, e.g. ["r0"] for the
+// alias of a repository, which doesn't exist.
+type Error struct {
+	Message string        `json:"message"`
+	Type    string        `json:"type,omitempty"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type Response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []Error         `json:"errors"`
+
+	// URL of the API, which the errors are reported with.
+	Url string `json:"-"`
+}
+
+// Decode parses the body of a response of the API at the URL.
+func Decode(url string, body []byte) (*Response, error) {
+	r := &Response{Url: url}
+
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Err returns the errors of the response, which aren't errors of single fields, or nil. Errors of
+// fields are errors of the whole response, when it has no data.
+func (r *Response) Err() error {
+	var messages []string
+
+	for _, e := range r.Errors {
+		if len(e.Path) == 0 || !r.hasData() {
+			messages = append(messages, e.Message)
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return &failures.GraphQlError{Url: r.Url, Messages: messages}
+}
+
+func (r *Response) hasData() bool {
+	return len(r.Data) > 0 && !bytes.Equal(r.Data, []byte("null"))
+}
+
+// Decode parses the data of the response to v.
+func (r *Response) Decode(v interface{}) error {
+	if !r.hasData() {
+		return &failures.GraphQlError{Url: r.Url, Messages: []string{"the response has no data"}}
+	}
+
+	return json.Unmarshal(r.Data, v)
+}
+
+// Field parses the data of the top-level field, or the alias, to v. Returns the errors of the
+// field, e.g. a repository, which doesn't exist.
+func (r *Response) Field(name string, v interface{}) error {
+	var messages []string
+
+	for _, e := range r.Errors {
+		if len(e.Path) > 0 && e.Path[0] == name {
+			messages = append(messages, e.Message)
+		}
+	}
+
+	if len(messages) > 0 {
+		return &failures.GraphQlError{Url: r.Url, Messages: messages}
+	}
+
+	var fields map[string]json.RawMessage
+
+	if err := r.Decode(&fields); err != nil {
+		return err
+	}
+
+	data, ok := fields[name]
+	if !ok || bytes.Equal(data, []byte("null")) {
+		return &failures.GraphQlError{Url: r.Url, Messages: []string{fmt.Sprintf("the response has no data of %s", name)}}
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Client sends the requests to the GraphQL API at the URL, and records their latencies by the
+// name of the API, see prom.ObserveGraphQl.
+type Client struct {
+	HttpClient *http.Client
+	Url        string
+	Api        string
+}
+
+func NewClient(httpClient *http.Client, url string, api string) *Client {
+	return &Client{HttpClient: httpClient, Url: url, Api: api}
+}
+
+// Do sends the request. Returns an error, if the status isn't successful, or the response has
+// errors, which aren't errors of single fields.
+func (c *Client) Do(ctx context.Context, request Request) (*Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+
+	res, err := c.HttpClient.Do(req)
+
+	prom.ObserveGraphQl(c.Api, res, err, time.Since(start))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &failures.StatusError{Url: c.Url, Status: res.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+
+	r, err := Decode(c.Url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Merges exported dataset bundles back to the database.
+//
+// Rows are matched to the existing rows with their natural keys, not with their ids, since the
+// ids of two databases are not comparable. For a matched row, empty columns are filled from the
+// bundle. Columns, which have a different value in both, are conflicts, and are resolved with
+// the OnConflict option.
+
+const (
+	OnConflictSkip      = "skip"
+	OnConflictOverwrite = "overwrite"
+)
+
+type Options struct {
+	DryRun     bool
+	OnConflict string
+}
+
+type Report struct {
+	DryRun    bool            `json:"dry_run"`
+	Snapshot  models.Snapshot `json:"snapshot"`
+	Tables    []TableReport   `json:"tables"`
+	Conflicts []Conflict      `json:"conflicts"`
+}
+
+type TableReport struct {
+	Table     string `json:"table"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Conflicts int    `json:"conflicts"`
+}
+
+type Conflict struct {
+	Table    string `json:"table"`
+	Key      string `json:"key"`
+	Column   string `json:"column"`
+	Existing string `json:"existing"`
+	Incoming string `json:"incoming"`
+}
+
+// Returned from the transaction to roll back a dry-run.
+var errDryRun = errors.New("dry-run")
+
+func ParseOptions(dryRun string, onConflict string) (Options, error) {
+	var o Options
+
+	o.DryRun = dryRun == "true" || dryRun == "1"
+
+	switch onConflict {
+	case "", OnConflictSkip:
+		o.OnConflict = OnConflictSkip
+	case OnConflictOverwrite:
+		o.OnConflict = OnConflictOverwrite
+	default:
+		return o, fmt.Errorf("unsupported on_conflict: %s", onConflict)
+	}
+
+	return o, nil
+}
+
+// Import reads a bundle archive and merges it to the database in a single transaction. In dry-run
+// mode the transaction is rolled back, and only the report is returned.
+func Import(db *gorm.DB, r io.Reader, o Options) (*Report, error) {
+	bundle, err := export.OpenBundle(r)
+	if err != nil {
+		return nil, apierror.BadRequest("%s", err)
+	}
+
+	defer bundle.Remove()
+
+	report := &Report{DryRun: o.DryRun, Conflicts: []Conflict{}}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := importTable[models.Repository](tx, bundle, "repositories", models.RepositoryKey, o, report); err != nil {
+			return err
+		}
+
+		if err := importTable[models.Commit](tx, bundle, "commits", models.CommitKey, o, report); err != nil {
+			return err
+		}
+
+		if err := importTable[models.Snapshot](tx, bundle, "snapshots", models.SnapshotKey, o, report); err != nil {
+			return err
+		}
+
+		// Record the imported bundle itself as a snapshot.
+		report.Snapshot = newSnapshot(bundle)
+
+		if err := tx.Where(models.Fields(&report.Snapshot, models.SnapshotKey)).FirstOrCreate(&report.Snapshot).Error; err != nil {
+			return err
+		}
+
+		if o.DryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func newSnapshot(bundle *export.Bundle) models.Snapshot {
+	var s models.Snapshot
+
+	s.GlassVersion = bundle.Manifest.GlassVersion
+	s.QualityMeasureVersion = bundle.Manifest.QualityMeasureVersion
+	s.Format = string(bundle.Manifest.Format)
+	s.ExportedAt = bundle.Manifest.CreatedAt.Format(time.RFC3339)
+	s.ImportedAt = time.Now().UTC().Format(time.RFC3339)
+
+	if f := bundle.File("repositories"); f != nil {
+		s.RepositoryCount = f.Rows
+	}
+
+	if f := bundle.File("commits"); f != nil {
+		s.CommitCount = f.Rows
+	}
+
+	return s
+}
+
+func importTable[T any](tx *gorm.DB, bundle *export.Bundle, table string, key []string, o Options, report *Report) error {
+	t := TableReport{Table: table}
+
+	err := export.ReadTable(bundle, table, func(row *T) error {
+		return mergeRow(tx, table, key, row, o, report, &t)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to import %s: %w", table, err)
+	}
+
+	report.Tables = append(report.Tables, t)
+
+	return nil
+}
+
+// Insert the row, if there is no row with the same key, otherwise update the columns, which are
+// empty in the database or, when overwriting, have a different value. Rows without a key, e.g.
+// repositories without an URL, can't be matched, so they are inserted.
+func mergeRow[T any](tx *gorm.DB, table string, key []string, row *T, o Options, report *Report, t *TableReport) error {
+	for _, value := range models.Values(row, key) {
+		if value == "" {
+			t.Inserted++
+			return tx.Omit("id").Create(row).Error
+		}
+	}
+
+	var existing T
+
+	result := tx.Where(models.Fields(row, key)).Limit(1).Find(&existing)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		t.Inserted++
+		return tx.Omit("id").Create(row).Error
+	}
+
+	var columns []string
+
+	for _, column := range models.Columns(row) {
+		if column != "id" {
+			columns = append(columns, column)
+		}
+	}
+
+	incoming := models.Values(row, columns)
+	current := models.Values(&existing, columns)
+
+	var updated []string
+
+	for i, column := range columns {
+		switch {
+		case incoming[i] == current[i] || isEmpty(incoming[i]):
+			continue
+		case isEmpty(current[i]) || o.OnConflict == OnConflictOverwrite:
+			updated = append(updated, column)
+		default:
+			t.Conflicts++
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Table:    table,
+				Key:      strings.Join(models.Values(row, key), "/"),
+				Column:   column,
+				Existing: current[i],
+				Incoming: incoming[i],
+			})
+		}
+	}
+
+	if len(updated) == 0 {
+		t.Unchanged++
+		return nil
+	}
+
+	t.Updated++
+
+	return tx.Model(&existing).Updates(models.Fields(row, updated)).Error
+}
+
+// Returns true, if the value is missing. A count of zero is a value, so it isn't overwritten.
+func isEmpty(value string) bool {
+	return value == ""
+}
@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newDatabase(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+name+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Repository{}, &models.Commit{}, &models.Snapshot{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// Returns the archive of a bundle of the repositories.
+func newArchive(t *testing.T, repositories ...models.Repository) *bytes.Buffer {
+	t.Helper()
+
+	db := newDatabase(t, "source")
+	db.Create(&repositories)
+
+	bundle, err := export.NewBundle(db, export.FormatJsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer bundle.Remove()
+
+	var archive bytes.Buffer
+
+	if err := bundle.Archive(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	return &archive
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   *models.Repository
+		incoming   models.Repository
+		onConflict string
+		want       models.Repository
+		table      TableReport
+		conflicts  []Conflict
+	}{
+		{
+			name:     "new row",
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			table:    TableReport{Table: "repositories", Inserted: 1},
+		},
+		{
+			name:     "same row",
+			existing: &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			table:    TableReport{Table: "repositories", Unchanged: 1},
+		},
+		{
+			name:     "empty column is filled",
+			existing: &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5", CommitCount: "10"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5", CommitCount: "10"},
+			table:    TableReport{Table: "repositories", Updated: 1},
+		},
+		{
+			name:     "empty incoming column is ignored",
+			existing: &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			table:    TableReport{Table: "repositories", Unchanged: 1},
+		},
+		{
+			name:     "zero count is a conflict",
+			existing: &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "0"},
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "3"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "0"},
+			table:    TableReport{Table: "repositories", Unchanged: 1, Conflicts: 1},
+			conflicts: []Conflict{
+				{Table: "repositories", Key: "github.com/o/a", Column: "open_issue_count", Existing: "0", Incoming: "3"},
+			},
+		},
+		{
+			name:     "incoming zero count is a conflict",
+			existing: &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "3"},
+			incoming: models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "0"},
+			want:     models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", OpenIssueCount: "3"},
+			table:    TableReport{Table: "repositories", Unchanged: 1, Conflicts: 1},
+			conflicts: []Conflict{
+				{Table: "repositories", Key: "github.com/o/a", Column: "open_issue_count", Existing: "3", Incoming: "0"},
+			},
+		},
+		{
+			name:       "conflict is overwritten",
+			existing:   &models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "5"},
+			incoming:   models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "0"},
+			onConflict: OnConflictOverwrite,
+			want:       models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a", StargazerCount: "0"},
+			table:      TableReport{Table: "repositories", Updated: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDatabase(t, "target")
+
+			if tt.existing != nil {
+				db.Create(tt.existing)
+			}
+
+			o, err := ParseOptions("", tt.onConflict)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := Import(db, newArchive(t, tt.incoming), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Tables[0] != tt.table {
+				t.Errorf("report = %+v, want %+v", report.Tables[0], tt.table)
+			}
+
+			if len(report.Conflicts) != len(tt.conflicts) {
+				t.Fatalf("conflicts = %+v, want %+v", report.Conflicts, tt.conflicts)
+			}
+
+			for i, c := range report.Conflicts {
+				if c != tt.conflicts[i] {
+					t.Errorf("conflict %d = %+v, want %+v", i, c, tt.conflicts[i])
+				}
+			}
+
+			var got models.Repository
+			db.First(&got)
+
+			tt.want.Id = got.Id
+			if got != tt.want {
+				t.Errorf("repository = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportShortNames(t *testing.T) {
+	db := newDatabase(t, "target")
+
+	// Enrich replaces the names with the short names of GitHub.
+	db.Create(&models.Repository{RepositoryName: "name", RepositoryUrl: "github.com/a/name", StargazerCount: "1"})
+
+	o, err := ParseOptions("", OnConflictOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := newArchive(t,
+		models.Repository{RepositoryName: "name", RepositoryUrl: "github.com/a/name", StargazerCount: "1"},
+		models.Repository{RepositoryName: "name", RepositoryUrl: "github.com/b/name", StargazerCount: "2"},
+	)
+
+	report, err := Import(db, archive, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (TableReport{Table: "repositories", Inserted: 1, Unchanged: 1}); report.Tables[0] != want {
+		t.Errorf("report = %+v, want %+v", report.Tables[0], want)
+	}
+
+	var stars []string
+	db.Model(&models.Repository{}).Order("repository_url").Pluck("stargazer_count", &stars)
+
+	if len(stars) != 2 || stars[0] != "1" || stars[1] != "2" {
+		t.Errorf("stargazer counts = %v, want [1 2]", stars)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	db := newDatabase(t, "target")
+
+	o, err := ParseOptions("true", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Import(db, newArchive(t, models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a"}), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Tables[0].Inserted != 1 {
+		t.Errorf("inserted = %d, want 1", report.Tables[0].Inserted)
+	}
+
+	var count int64
+	db.Model(&models.Repository{}).Count(&count)
+
+	if count != 0 {
+		t.Errorf("repositories after a dry-run = %d, want 0", count)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	if _, err := ParseOptions("", "replace"); err == nil {
+		t.Error("ParseOptions of an unsupported on_conflict succeeded")
+	}
+}
@@ -0,0 +1,262 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/metrics/prom"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/plugins"
+	"gorm.io/gorm"
+)
+
+// Runs the stages of a plugin in the background, one job at a time, in the order the jobs were
+// created. Jobs are stored in the database, so the queued jobs survive restarts. Each job runs
+// with the stage settings of the configuration and the overrides of its request.
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+type Queue struct {
+	Database *gorm.DB
+	Config   *config.Config
+
+	wake chan struct{}
+}
+
+// NewQueue returns a queue, which runs the jobs in the background, until the context is
+// cancelled. A running job finishes first. Jobs, which were running when the service stopped, are
+// marked as failed.
+func NewQueue(ctx context.Context, db *gorm.DB, c *config.Config) *Queue {
+	q := new(Queue)
+
+	q.Database = db
+	q.Config = c
+	q.wake = make(chan struct{}, 1)
+
+	err := db.Model(&models.Job{}).Where("status = ?", StatusRunning).Updates(models.Job{
+		Status:     StatusFailed,
+		Error:      "interrupted, the service was stopped",
+		FinishedAt: now(),
+	}).Error
+	if err != nil {
+		slog.Error("failing the interrupted jobs", logging.KeyError, err)
+	}
+
+	q.measureDepth()
+
+	go q.work(ctx)
+
+	return q
+}
+
+// Enqueue validates the request, and queues the job.
+func (q *Queue) Enqueue(i models.CreateJobInput) (*models.Job, error) {
+	var fields []apierror.FieldError
+
+	requirements, err := plugins.Requirements(i.Plugin)
+	if err != nil {
+		fields = append(fields, apierror.FieldError{Field: "plugin", Code: "oneof", Message: err.Error()})
+	}
+
+	c, err := q.Config.Override(i.Overrides)
+	if err != nil {
+		fields = append(fields, apierror.FieldError{Field: "overrides", Code: "config", Message: err.Error()})
+	}
+
+	if len(fields) > 0 {
+		return nil, apierror.Validation(fields)
+	}
+
+	// Missing configuration is an error of the service, not of the request.
+	if err := c.Validate(requirements...); err != nil {
+		return nil, err
+	}
+
+	return q.create(models.Job{
+		Plugin:    i.Plugin,
+		Count:     i.Count,
+		Overrides: i.Overrides,
+		Sources:   i.Sources,
+		Stages:    jobStages(c.Stages),
+	})
+}
+
+// EnqueueRetry queues a job, which runs the stage of the plugin again for the repositories,
+// which failed in it.
+func (q *Queue) EnqueueRetry(plugin string, stage string, repositories []string) (*models.Job, error) {
+	requirements, err := plugins.Requirements(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := q.Config.Override(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(requirements...); err != nil {
+		return nil, err
+	}
+
+	return q.create(models.Job{
+		Plugin:       plugin,
+		Stage:        stage,
+		Repositories: repositories,
+		Stages:       jobStages(c.Stages),
+	})
+}
+
+// Stores the job as queued, and wakes the worker.
+func (q *Queue) create(job models.Job) (*models.Job, error) {
+	job.Status = StatusQueued
+	job.CreatedAt = now()
+
+	if err := q.Database.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	q.measureDepth()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return &job, nil
+}
+
+// Runs the oldest queued job, and waits for new jobs, when there are none, until the context is
+// cancelled.
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		var job models.Job
+
+		err := q.Database.Where("status = ?", StatusQueued).Order("id").First(&job).Error
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			q.wait(ctx, nil)
+		case err != nil:
+			slog.Error("reading the queued jobs", logging.KeyError, err)
+			q.wait(ctx, time.After(time.Second))
+		default:
+			q.run(&job)
+		}
+	}
+}
+
+// Waits for a new job, the timer, when it isn't nil, or the cancellation of the context.
+func (q *Queue) wait(ctx context.Context, timer <-chan time.Time) {
+	select {
+	case <-ctx.Done():
+	case <-q.wake:
+	case <-timer:
+	}
+}
+
+func (q *Queue) run(job *models.Job) {
+	logger := slog.With(logging.KeyJob, job.Id, logging.KeyPlugin, job.Plugin)
+
+	if job.Stage != "" {
+		logger = logger.With(logging.KeyStage, job.Stage)
+	}
+
+	logger.Info("job started")
+	q.update(job, models.Job{Status: StatusRunning, StartedAt: now()})
+	q.measureDepth()
+
+	if err := q.runPlugin(job); err != nil {
+		logger.Error("job failed", logging.KeyError, err)
+		q.update(job, models.Job{Status: StatusFailed, Error: err.Error(), FinishedAt: now()})
+		return
+	}
+
+	logger.Info("job succeeded")
+	q.update(job, models.Job{Status: StatusSucceeded, FinishedAt: now()})
+}
+
+// Runs every stage of the plugin with the stage settings of the job, or only the stage of a retry.
+func (q *Queue) runPlugin(job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	c := *q.Config
+
+	if c.Stages, err = configStages(job.Stages); err != nil {
+		return err
+	}
+
+	p, err := plugins.New(job.Plugin, q.Database, &c, job.Id)
+	if err != nil {
+		return err
+	}
+
+	if job.Stage != "" {
+		return p.Retry(job.Stage, job.Repositories)
+	}
+
+	return plugins.Run(p, job.Count, job.Sources)
+}
+
+func (q *Queue) update(job *models.Job, values models.Job) {
+	if err := q.Database.Model(job).Updates(values).Error; err != nil {
+		slog.Error("updating the job", logging.KeyJob, job.Id, logging.KeyError, err)
+	}
+}
+
+// Sets the metric of the queued jobs.
+func (q *Queue) measureDepth() {
+	var depth int64
+
+	if err := q.Database.Model(&models.Job{}).Where("status = ?", StatusQueued).Count(&depth).Error; err != nil {
+		slog.Error("counting the queued jobs", logging.KeyError, err)
+		return
+	}
+
+	prom.JobQueueDepth.Set(float64(depth))
+}
+
+// Converts the stage settings of the configuration to the settings stored with the job.
+func jobStages(stages map[string]config.Stage) map[string]models.Stage {
+	j := make(map[string]models.Stage, len(stages))
+
+	for name, s := range stages {
+		j[name] = models.Stage{Workers: s.Workers, Timeout: s.Timeout.String(), BatchSize: s.BatchSize, DiskBudget: s.DiskBudget}
+	}
+
+	return j
+}
+
+// Converts the stage settings of the job back to the configuration.
+func configStages(stages map[string]models.Stage) (map[string]config.Stage, error) {
+	c := make(map[string]config.Stage, len(stages))
+
+	for name, s := range stages {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("timeout of stage %s: %w", name, err)
+		}
+
+		c[name] = config.Stage{Workers: s.Workers, Timeout: timeout, BatchSize: s.BatchSize, DiskBudget: s.DiskBudget}
+	}
+
+	return c, nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestNewQueueFailsInterruptedJobs(t *testing.T) {
+	db := newDatabase(t)
+	db.Create(&models.Job{Plugin: "go", Status: StatusRunning})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	NewQueue(ctx, db, &config.Config{})
+
+	var job models.Job
+	db.First(&job)
+
+	if job.Status != StatusFailed || job.FinishedAt == "" {
+		t.Errorf("job = %+v, want failed", job)
+	}
+}
+
+func TestWorkStops(t *testing.T) {
+	q := &Queue{Database: newDatabase(t), Config: &config.Config{}, wake: make(chan struct{}, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		q.work(ctx)
+		close(done)
+	}()
+
+	// The worker waits for new jobs, until it is stopped.
+	select {
+	case <-done:
+		t.Fatal("worker stopped without jobs")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker didn't stop, when the context was cancelled")
+	}
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Queries slower than this are logged as warnings.
+const slowQuery = 200 * time.Millisecond
+
+// Gorm returns a logger of GORM, which logs the failed and the slow queries, and every query at
+// the debug level. Missing rows are not errors, the API responds to them with 404.
+func Gorm() logger.Interface {
+	return gormLogger{}
+}
+
+type gormLogger struct{}
+
+func (l gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+
+	level, msg := slog.LevelDebug, "query"
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case elapsed > slowQuery:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+
+	attrs := []slog.Attr{slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("duration", elapsed)}
+
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String(KeyError, err.Error()))
+	}
+
+	slog.Default().LogAttrs(ctx, level, msg, attrs...)
+}
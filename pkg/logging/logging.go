@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/config"
+)
+
+// Structured logging of Glass. Every line has a level and the fields of its context, e.g. the
+// job, the stage and the repository, and is written as logfmt or JSON, see config.Log. The
+// default logger of slog is used everywhere, so the lines of the standard log package, gin and
+// GORM have the same format.
+
+// Fields of the lines.
+const (
+	KeyJob        = "job"
+	KeyPlugin     = "plugin"
+	KeyStage      = "stage"
+	KeyRepository = "repository"
+	KeyError      = "error"
+)
+
+// New returns a logger, which writes the lines of the level and above to w.
+func New(w io.Writer, c config.Log) *slog.Logger {
+	options := &slog.HandlerOptions{Level: c.Level}
+
+	if c.Format == config.LogFormatJson {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// Setup sets the default logger, which writes to the standard error. The debug lines of gin,
+// which have a format of their own, are turned off, see Gin.
+func Setup(c config.Log) {
+	slog.SetDefault(New(os.Stderr, c))
+
+	gin.SetMode(gin.ReleaseMode)
+}
+
+// Gin logs the requests, instead of the default logger of gin. Routes are logged by their
+// templates, e.g. /api/glass/v1/job/:id, and the client errors are logged as warnings.
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		} else if status >= 400 {
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client", c.ClientIP()),
+		}
+
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String(KeyError, c.Errors.String()))
+		}
+
+		slog.Default().LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
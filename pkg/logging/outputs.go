@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Outputs keeps the output of the commands, which the stages run for the repositories. Within a
+// job, the output is stored as the logs of the job, and can be read from the API. Outside jobs,
+// e.g. in the CLI, the output is logged at the debug level.
+type Outputs struct {
+	Database *gorm.DB
+	Job      int
+	Logger   *slog.Logger
+}
+
+// NewOutputs returns the outputs of the job. Zero job logs the output instead of storing it.
+func NewOutputs(db *gorm.DB, job int, logger *slog.Logger) *Outputs {
+	return &Outputs{Database: db, Job: job, Logger: logger}
+}
+
+// Record keeps the output of the command, and the error it failed with.
+func (o *Outputs) Record(stage string, repository string, command string, output string, err error) {
+	logger := o.Logger.With(KeyStage, stage, KeyRepository, repository)
+
+	if o.Job == 0 {
+		args := []any{"command", command, "output", output}
+		if err != nil {
+			args = append(args, KeyError, err)
+		}
+
+		logger.Debug("command finished", args...)
+		return
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	l := models.JobLog{
+		JobId:          o.Job,
+		RepositoryName: repository,
+		Stage:          stage,
+		Command:        command,
+		Output:         output,
+		Error:          message,
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if err := o.Database.Create(&l).Error; err != nil {
+		logger.Error("storing the output of a command", "command", command, KeyError, err)
+	}
+}
@@ -0,0 +1,94 @@
+package grafana
+
+// Pipeline throughput and failures, the requests to GitHub and SourceGraph, the commands of the
+// stages, the job queue and the API.
+func collectionDashboard() *builder {
+	b := newDashboard("glass-collection", "Glass Collection", "Throughput and failures of the collection pipeline, and the health of the APIs it depends on.")
+
+	b.panel(TypeTimeseries, "Pipeline throughput (repositories / min)", UnitPerMinute, "Repositories written by the fetch, enrich, clone and count stages.",
+		query{b.perMinute("glass_repositories_discovered_total"), "discovered"},
+		query{b.perMinute("glass_repositories_enriched_total"), "enriched"},
+		query{b.perMinute("glass_repositories_measured_total", "stage"), "measured ({{stage}})"},
+	)
+	b.panel(TypeTimeseries, "Failures (repositories / min)", UnitPerMinute, b.help("glass_repository_failures_total"),
+		query{b.perMinute("glass_repository_failures_total", "stage", "class"), "{{stage}} {{class}}"},
+	)
+
+	b.panel(TypeStat, "GitHub rate limit remaining", UnitNone, b.help("glass_github_rate_limit_remaining"),
+		query{b.value("glass_github_rate_limit_remaining", "token"), "token {{token}}"},
+	)
+	b.panel(TypeTimeseries, "GitHub requests by token (requests / min)", UnitPerMinute, b.help("glass_github_token_requests_total"),
+		query{b.perMinute("glass_github_token_requests_total", "token"), "token {{token}}"},
+	)
+	b.panel(TypeTimeseries, "GitHub retries (requests / min)", UnitPerMinute, b.help("glass_github_retries_total"),
+		query{b.perMinute("glass_github_retries_total", "reason"), "{{reason}}"},
+	)
+	b.panel(TypeTimeseries, "Discovery searches (searches / min)", UnitPerMinute, b.help("glass_discovery_searches_total"),
+		query{b.perMinute("glass_discovery_searches_total", "source", "result"), "{{source}} {{result}}"},
+	)
+	b.panel(TypeTimeseries, "GraphQL requests (requests / min)", UnitPerMinute, b.help("glass_graphql_request_duration_seconds"),
+		query{b.perMinute("glass_graphql_request_duration_seconds", "api", "status"), "{{api}} {{status}}"},
+	)
+	b.panel(TypeTimeseries, "GraphQL latency", UnitSeconds, b.help("glass_graphql_request_duration_seconds"),
+		query{b.quantile(0.5, "glass_graphql_request_duration_seconds", "api"), "p50 {{api}}"},
+		query{b.quantile(0.95, "glass_graphql_request_duration_seconds", "api"), "p95 {{api}}"},
+	)
+	b.panel(TypeTimeseries, "Job queue depth", UnitNone, b.help("glass_job_queue_depth"),
+		query{b.value("glass_job_queue_depth"), "queued"},
+	)
+
+	b.panel(TypeTimeseries, "Clone duration", UnitSeconds, b.help("glass_clone_duration_seconds"),
+		query{b.quantile(0.5, "glass_clone_duration_seconds"), "p50"},
+		query{b.quantile(0.95, "glass_clone_duration_seconds"), "p95"},
+	)
+	b.panel(TypeTimeseries, "Clone size", UnitBytes, b.help("glass_clone_bytes"),
+		query{b.quantile(0.5, "glass_clone_bytes"), "p50"},
+		query{b.quantile(0.95, "glass_clone_bytes"), "p95"},
+	)
+	b.panel(TypeTimeseries, "gocloc duration", UnitSeconds, b.help("glass_gocloc_duration_seconds"),
+		query{b.quantile(0.5, "glass_gocloc_duration_seconds"), "p50"},
+		query{b.quantile(0.95, "glass_gocloc_duration_seconds"), "p95"},
+	)
+	b.panel(TypeTimeseries, "Module downloads (modules / min)", UnitPerMinute, b.help("glass_module_downloads_total"),
+		query{b.perMinute("glass_module_downloads_total", "result"), "{{result}}"},
+	)
+
+	b.panel(TypeTimeseries, "API requests (requests / min)", UnitPerMinute, b.help("glass_http_requests_total"),
+		query{b.perMinute("glass_http_requests_total", "route", "status"), "{{route}} {{status}}"},
+	)
+	b.panel(TypeTimeseries, "API latency (p95)", UnitSeconds, b.help("glass_http_request_duration_seconds"),
+		query{b.quantile(0.95, "glass_http_request_duration_seconds", "route"), "{{route}}"},
+	)
+	b.panel(TypeTimeseries, "API requests in flight", UnitNone, b.help("glass_http_requests_in_flight"),
+		query{b.value("glass_http_requests_in_flight", "route"), "{{route}}"},
+	)
+
+	return b
+}
+
+// Size of the dataset, the repositories the stages are done with, and the distributions of the
+// Quality Measure and the library ratio, which are calculated from the database.
+func datasetDashboard() *builder {
+	b := newDashboard("glass-dataset", "Glass Dataset", "Size, completeness and quality of the dataset in the database.")
+
+	b.panel(TypeTimeseries, "Dataset size (repositories)", UnitNone, b.help("glass_dataset_repositories"),
+		query{b.value("glass_dataset_repositories", "plugin", "language"), "{{plugin}} {{language}}"},
+	)
+	b.panel(TypeTimeseries, "Complete metadata", UnitPercent, b.help("glass_dataset_complete_ratio"),
+		query{b.value("glass_dataset_complete_ratio", "stage"), "{{stage}}"},
+	)
+
+	b.panel(TypeBarGauge, "Quality Measure distribution (repositories)", UnitNone, "Repositories by their Quality Measure, between 0 and 5.",
+		query{b.value("glass_dataset_quality_measure_repositories", "le"), "{{le}}"},
+	)
+	b.panel(TypeTimeseries, "Quality Measure", UnitNone, "Median and 90th percentile of the Quality Measure of the repositories, estimated from the buckets.",
+		query{b.bucketQuantile(0.5, "glass_dataset_quality_measure_repositories"), "p50"},
+		query{b.bucketQuantile(0.9, "glass_dataset_quality_measure_repositories"), "p90"},
+	)
+
+	b.panel(TypeTimeseries, "Library ratio (median)", UnitNone, b.help("glass_dataset_library_ratio_median"),
+		query{b.value("glass_dataset_library_ratio_median"), "median"},
+	)
+
+	return b
+}
@@ -0,0 +1,355 @@
+package grafana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/metrics/prom"
+)
+
+// Dashboards of Grafana, generated from the definitions of the metrics, see prom.Definitions.
+// Panels refer to the metrics by their definitions, so a renamed or removed metric, or a label,
+// which a metric doesn't have, fails the generation instead of leaving an empty panel behind,
+// and every metric needs a panel.
+
+// Uid of the provisioned Prometheus datasource, which the panels query.
+const DatasourceUid = "glass-prometheus"
+
+var datasource = Datasource{Type: "prometheus", Uid: DatasourceUid}
+
+// Dashboard is the JSON model of a Grafana dashboard.
+type Dashboard struct {
+	Uid           string    `json:"uid"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description,omitempty"`
+	Tags          []string  `json:"tags"`
+	Editable      bool      `json:"editable"`
+	Refresh       string    `json:"refresh"`
+	SchemaVersion int       `json:"schemaVersion"`
+	Time          TimeRange `json:"time"`
+	Panels        []Panel   `json:"panels"`
+}
+
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Panel struct {
+	Id          int         `json:"id"`
+	Type        string      `json:"type"`
+	Title       string      `json:"title"`
+	Description string      `json:"description,omitempty"`
+	GridPos     GridPos     `json:"gridPos"`
+	Datasource  Datasource  `json:"datasource"`
+	Targets     []Target    `json:"targets"`
+	FieldConfig FieldConfig `json:"fieldConfig"`
+}
+
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type Datasource struct {
+	Type string `json:"type"`
+	Uid  string `json:"uid"`
+}
+
+type Target struct {
+	RefId        string     `json:"refId"`
+	Expr         string     `json:"expr"`
+	LegendFormat string     `json:"legendFormat,omitempty"`
+	Format       string     `json:"format,omitempty"`
+	Datasource   Datasource `json:"datasource"`
+}
+
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+type FieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+// Types of the panels.
+const (
+	TypeTimeseries = "timeseries"
+	TypeStat       = "stat"
+	TypeBarGauge   = "bargauge"
+)
+
+// Format of the targets, which turns cumulative buckets to the counts of each bucket.
+const formatHeatmap = "heatmap"
+
+// Units of the panels.
+const (
+	UnitSeconds   = "s"
+	UnitBytes     = "bytes"
+	UnitPerMinute = "short"
+	UnitPercent   = "percentunit"
+	UnitNone      = "none"
+)
+
+// Window of the rates and the quantiles.
+const window = "5m"
+
+// Builder of a dashboard. Panels are laid out two in a row, in the order they are added.
+type builder struct {
+	dashboard Dashboard
+	used      map[string]bool
+
+	// Metrics and labels of the panels, which aren't defined, see metric.
+	errs []error
+}
+
+func newDashboard(uid string, title string, description string) *builder {
+	return &builder{
+		dashboard: Dashboard{
+			Uid:           uid,
+			Title:         title,
+			Description:   description,
+			Tags:          []string{"glass"},
+			Editable:      true,
+			Refresh:       "30s",
+			SchemaVersion: 39,
+			Time:          TimeRange{From: "now-6h", To: "now"},
+			Panels:        []Panel{},
+		},
+		used: make(map[string]bool),
+	}
+}
+
+// Query of a panel, with the legend of its series, e.g. "{{stage}}".
+type query struct {
+	expr   string
+	legend string
+}
+
+// Adds a panel of the queries. The description is the help of the metric, unless it is given.
+func (b *builder) panel(kind string, title string, unit string, description string, queries ...query) {
+	i := len(b.dashboard.Panels)
+
+	p := Panel{
+		Id:          i + 1,
+		Type:        kind,
+		Title:       title,
+		Description: description,
+		GridPos:     GridPos{X: (i % 2) * 12, Y: (i / 2) * 8, W: 12, H: 8},
+		Datasource:  datasource,
+		FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: unit}},
+	}
+
+	for j, q := range queries {
+		t := Target{RefId: string(rune('A' + j)), Expr: q.expr, LegendFormat: q.legend, Datasource: datasource}
+
+		// Bar gauges show the distributions of the buckets.
+		if kind == TypeBarGauge {
+			t.Format = formatHeatmap
+		}
+
+		p.Targets = append(p.Targets, t)
+	}
+
+	b.dashboard.Panels = append(b.dashboard.Panels, p)
+}
+
+// Returns the definition of the metric, and marks it as used. A metric or a label, which is not
+// defined, is recorded as an error of the dashboard, see Dashboards.
+func (b *builder) metric(name string, labels ...string) prom.Definition {
+	for _, d := range prom.Definitions {
+		if d.Name != name {
+			continue
+		}
+
+		for _, l := range labels {
+			if !contains(d.Labels, l) {
+				b.errs = append(b.errs, fmt.Errorf("%s: metric %s has no label %s", b.dashboard.Uid, name, l))
+			}
+		}
+
+		b.used[name] = true
+
+		return d
+	}
+
+	b.errs = append(b.errs, fmt.Errorf("%s: metric %s is not defined", b.dashboard.Uid, name))
+
+	return prom.Definition{Name: name}
+}
+
+// Per-minute rate of the counter, or of the observations of the histogram, summed by the labels.
+func (b *builder) perMinute(name string, by ...string) string {
+	d := b.metric(name, by...)
+
+	series := d.Name
+	if d.Type == prom.TypeHistogram {
+		series += "_count"
+	}
+
+	return fmt.Sprintf("sum%s(rate(%s[%s])) * 60", grouping(by), series, window)
+}
+
+// Quantile of the histogram, by the labels.
+func (b *builder) quantile(q float64, name string, by ...string) string {
+	d := b.metric(name, by...)
+
+	return fmt.Sprintf("histogram_quantile(%g, sum%s(rate(%s_bucket[%s])))", q, grouping(append(by, "le")), d.Name, window)
+}
+
+// Quantile of the gauge, which has cumulative buckets by the label le, like a histogram.
+func (b *builder) bucketQuantile(q float64, name string) string {
+	d := b.metric(name, "le")
+
+	return fmt.Sprintf("histogram_quantile(%g, %s)", q, d.Name)
+}
+
+// Current value of the gauge, summed by the labels.
+func (b *builder) value(name string, by ...string) string {
+	d := b.metric(name, by...)
+
+	if len(by) == 0 {
+		return d.Name
+	}
+
+	return fmt.Sprintf("sum%s(%s)", grouping(by), d.Name)
+}
+
+// Help of the metric, for the description of its panel.
+func (b *builder) help(name string) string {
+	return b.metric(name).Help
+}
+
+func grouping(by []string) string {
+	if len(by) == 0 {
+		return ""
+	}
+
+	return " by (" + strings.Join(by, ", ") + ") "
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Dashboards returns the dashboards of Glass. Returns an error, if a panel refers to a metric or
+// a label, which is not defined, or a metric of Glass has no panel in any of them.
+func Dashboards() ([]Dashboard, error) {
+	builders := []*builder{collectionDashboard(), datasetDashboard()}
+
+	used := make(map[string]bool)
+
+	var dashboards []Dashboard
+
+	for _, b := range builders {
+		if len(b.errs) > 0 {
+			return nil, errors.Join(b.errs...)
+		}
+
+		for name := range b.used {
+			used[name] = true
+		}
+
+		dashboards = append(dashboards, b.dashboard)
+	}
+
+	var missing []string
+
+	for _, d := range prom.Definitions {
+		if !used[d.Name] {
+			missing = append(missing, d.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("metrics without a panel: %s", strings.Join(missing, ", "))
+	}
+
+	return dashboards, nil
+}
+
+// Files of the provisioning of Grafana in the directory, which docker-compose mounts.
+const (
+	DashboardsDir  = "dashboards"
+	ProviderFile   = "provisioning/dashboards/glass.yml"
+	DatasourceFile = "provisioning/datasources/glass.yml"
+)
+
+// Path of the dashboards in the Grafana container.
+const containerDashboardsDir = "/var/lib/grafana/dashboards/glass"
+
+// Write writes the dashboards, and the provisioning files of the dashboards and the Prometheus
+// datasource at the URL to the directory. Returns the paths of the written files.
+func Write(dir string, prometheusUrl string) ([]string, error) {
+	dashboards, err := Dashboards()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{
+		ProviderFile: []byte(fmt.Sprintf(`apiVersion: 1
+
+providers:
+  - name: glass
+    folder: Glass
+    type: file
+    disableDeletion: true
+    allowUiUpdates: false
+    options:
+      path: %s
+`, containerDashboardsDir)),
+		DatasourceFile: []byte(fmt.Sprintf(`apiVersion: 1
+
+datasources:
+  - name: Prometheus
+    uid: %s
+    type: prometheus
+    access: proxy
+    url: %s
+    isDefault: true
+`, DatasourceUid, prometheusUrl)),
+	}
+
+	for _, d := range dashboards {
+		b, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		files[filepath.Join(DashboardsDir, d.Uid+".json")] = append(b, '\n')
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
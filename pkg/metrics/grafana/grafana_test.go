@@ -0,0 +1,73 @@
+package grafana
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/haapjari/glass/pkg/metrics/prom"
+)
+
+var (
+	metricName = regexp.MustCompile(`glass_[a-z_]+`)
+	byLabels   = regexp.MustCompile(`by \(([^)]*)\)`)
+)
+
+func TestDashboards(t *testing.T) {
+	dashboards, err := Dashboards()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	definitions := make(map[string]prom.Definition)
+	for _, d := range prom.Definitions {
+		definitions[d.Name] = d
+	}
+
+	for _, dashboard := range dashboards {
+		for _, p := range dashboard.Panels {
+			for _, target := range p.Targets {
+				names := metricName.FindAllString(target.Expr, -1)
+				if len(names) == 0 {
+					t.Errorf("%s: %s: query %q has no metric", dashboard.Uid, p.Title, target.Expr)
+				}
+
+				for _, name := range names {
+					// Series of the histograms.
+					d, ok := definitions[name]
+					for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+						if base, found := strings.CutSuffix(name, suffix); !ok && found {
+							d, ok = definitions[base]
+						}
+					}
+
+					if !ok {
+						t.Errorf("%s: %s: metric %s is not defined", dashboard.Uid, p.Title, name)
+						continue
+					}
+
+					for _, m := range byLabels.FindAllStringSubmatch(target.Expr, -1) {
+						for _, label := range strings.Split(m[1], ", ") {
+							if label != "le" && !contains(d.Labels, label) {
+								t.Errorf("%s: %s: metric %s has no label %s", dashboard.Uid, p.Title, name, label)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestUndefinedMetrics(t *testing.T) {
+	b := newDashboard("test", "Test", "")
+
+	b.panel(TypeTimeseries, "Misspelled", UnitNone, "",
+		query{b.perMinute("glass_repositories_discoverd_total"), ""},
+		query{b.value("glass_job_queue_depth", "stage"), ""},
+	)
+
+	if len(b.errs) != 2 {
+		t.Errorf("errors = %v, want the metric and the label", b.errs)
+	}
+}
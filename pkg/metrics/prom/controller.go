@@ -19,8 +19,9 @@ func NewProm() *Prom {
 	return p
 }
 
+// PrometheusHandler serves the metrics of the Registry.
 func PrometheusHandler() gin.HandlerFunc {
-	h := promhttp.Handler()
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
 
 	return func(c *gin.Context) {
 		h.ServeHTTP(c.Writer, c.Request)
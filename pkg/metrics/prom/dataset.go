@@ -0,0 +1,267 @@
+package prom
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/quality"
+	"gorm.io/gorm"
+)
+
+// Metrics of the dataset are calculated from the database, instead of being recorded by the
+// stages, so they describe every repository, also the imported ones and the ones collected
+// before the service was started.
+
+// Label of the repositories, which have no primary language, or which language no plugin collects.
+const unknownLabel = "unknown"
+
+// Upper bounds of the buckets of the Quality Measure, from 0.5 to quality.MaxScore.
+var qualityBuckets = []float64{0.5, 1, 1.5, 2, 2.5, 3, 3.5, 4, 4.5, quality.MaxScore}
+
+// Columns, which a stage writes, by the stage. A repository is complete in a stage, when every
+// column has a value. License and latest release are left out, since repositories may have neither.
+var stageColumns = map[string][]string{
+	config.StageEnrich: {"open_issue_count", "closed_issue_count", "commit_count", "primary_language", "creation_date", "stargazer_count"},
+	config.StageClone:  {"original_codebase_size"},
+	config.StageCount:  {"library_codebase_size"},
+}
+
+type Dataset struct {
+	Database *gorm.DB
+
+	// Names of the plugins by the primary language of the repositories they collect.
+	Plugins map[string]string
+
+	// Labels of the repositories of the previous refresh, so the languages, which are no longer
+	// in the database, are removed.
+	labels map[[2]string]bool
+}
+
+func NewDataset(db *gorm.DB, plugins map[string]string) *Dataset {
+	d := new(Dataset)
+
+	d.Database = db
+	d.Plugins = plugins
+
+	return d
+}
+
+// Watch refreshes the metrics of the dataset now, and then at the interval in the background,
+// until the context is cancelled. Zero interval doesn't refresh them at all.
+func (d *Dataset) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.Refresh(); err != nil {
+				slog.Warn("refreshing the dataset metrics", logging.KeyError, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Refresh calculates the metrics of the dataset from the repositories in the database. Each
+// gauge is set, when its query is done, so a failing query leaves the gauges of the others.
+func (d *Dataset) Refresh() error {
+	total, err := d.refreshRepositories()
+	if err != nil {
+		return err
+	}
+
+	if err := d.refreshCompleteRatios(total); err != nil {
+		return err
+	}
+
+	if err := d.refreshQualityMeasure(); err != nil {
+		return err
+	}
+
+	return d.refreshLibraryRatio()
+}
+
+// Counts the repositories by the primary language, and returns the total.
+func (d *Dataset) refreshRepositories() (int64, error) {
+	var rows []struct {
+		PrimaryLanguage string
+		Count           int64
+	}
+
+	err := d.Database.Model(&models.Repository{}).
+		Select("COALESCE(primary_language, '') AS primary_language, COUNT(*) AS count").
+		Group("COALESCE(primary_language, '')").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	counts := make(map[[2]string]int64, len(rows))
+	total := int64(0)
+
+	for _, r := range rows {
+		plugin, language := unknownLabel, unknownLabel
+
+		if r.PrimaryLanguage != "" {
+			language = r.PrimaryLanguage
+		}
+
+		if p, ok := d.Plugins[r.PrimaryLanguage]; ok {
+			plugin = p
+		}
+
+		counts[[2]string{plugin, language}] += r.Count
+		total += r.Count
+	}
+
+	for labels, n := range counts {
+		DatasetRepositories.WithLabelValues(labels[0], labels[1]).Set(float64(n))
+	}
+
+	for labels := range d.labels {
+		if _, ok := counts[labels]; !ok {
+			DatasetRepositories.DeleteLabelValues(labels[0], labels[1])
+		}
+	}
+
+	d.labels = make(map[[2]string]bool, len(counts))
+	for labels := range counts {
+		d.labels[labels] = true
+	}
+
+	return total, nil
+}
+
+// Counts the repositories, which have every column of the stage, of the total.
+func (d *Dataset) refreshCompleteRatios(total int64) error {
+	for stage, columns := range stageColumns {
+		q := d.Database.Model(&models.Repository{})
+
+		for _, column := range columns {
+			q = q.Where("COALESCE(" + column + ", '') <> ''")
+		}
+
+		var complete int64
+
+		if err := q.Count(&complete).Error; err != nil {
+			return err
+		}
+
+		DatasetCompleteRatio.WithLabelValues(stage).Set(ratio(complete, total))
+	}
+
+	return nil
+}
+
+// Calculates the distribution of the Quality Measure. Factors are ranked against every other
+// repository, so the columns of the factors are read of every repository.
+func (d *Dataset) refreshQualityMeasure() error {
+	var repositories []models.Repository
+
+	if err := d.Database.Select(quality.Columns).Find(&repositories).Error; err != nil {
+		return err
+	}
+
+	// Repositories without any factor have no Quality Measure, instead of the lowest one.
+	var measures []float64
+
+	for _, s := range quality.Measure(repositories) {
+		if len(s.Factors) > 0 {
+			measures = append(measures, s.QualityMeasure)
+		}
+	}
+
+	for _, le := range qualityBuckets {
+		n := 0
+
+		for _, m := range measures {
+			if m <= le {
+				n++
+			}
+		}
+
+		DatasetQualityMeasure.WithLabelValues(strconv.FormatFloat(le, 'f', -1, 64)).Set(float64(n))
+	}
+
+	DatasetQualityMeasure.WithLabelValues("+Inf").Set(float64(len(measures)))
+
+	return nil
+}
+
+// Calculates the median of the library ratios of the repositories, which both codebase sizes
+// are measured of.
+func (d *Dataset) refreshLibraryRatio() error {
+	var repositories []models.Repository
+
+	err := d.Database.Select("original_codebase_size", "library_codebase_size").
+		Where("COALESCE(original_codebase_size, '') <> '' AND COALESCE(library_codebase_size, '') <> ''").
+		Find(&repositories).Error
+	if err != nil {
+		return err
+	}
+
+	DatasetLibraryRatio.Set(median(libraryRatios(repositories)))
+
+	return nil
+}
+
+func ratio(n int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n) / float64(total)
+}
+
+// Ratios of the library codebase size to the original codebase size of the repositories, which
+// both sizes are measured of.
+func libraryRatios(repositories []models.Repository) []float64 {
+	var ratios []float64
+
+	for _, r := range repositories {
+		original, err := strconv.Atoi(r.OriginalCodebaseSize)
+		if err != nil || original <= 0 {
+			continue
+		}
+
+		library, err := strconv.Atoi(r.LibraryCodebaseSize)
+		if err != nil || library < 0 {
+			continue
+		}
+
+		ratios = append(ratios, float64(library)/float64(original))
+	}
+
+	return ratios
+}
+
+// Median of the values, zero without values.
+func median(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+
+	sort.Float64s(values)
+
+	if n%2 == 1 {
+		return values[n/2]
+	}
+
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,73 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRefresh(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Repository{}); err != nil {
+		t.Fatal(err)
+	}
+
+	enriched := models.Repository{OpenIssueCount: "1", ClosedIssueCount: "2", CommitCount: "30", CreationDate: "2020-01-01T00:00:00Z", StargazerCount: "4", PrimaryLanguage: "Go"}
+
+	a, b := enriched, enriched
+	a.RepositoryName, a.OriginalCodebaseSize, a.LibraryCodebaseSize = "github.com/o/a", "100", "50"
+	b.RepositoryName, b.OriginalCodebaseSize, b.LibraryCodebaseSize, b.CommitCount = "github.com/o/b", "100", "150", "10"
+
+	db.Create(&[]models.Repository{
+		a,
+		b,
+		{RepositoryName: "github.com/o/c", PrimaryLanguage: "Rust"},
+		{RepositoryName: "github.com/o/d"},
+	})
+
+	d := NewDataset(db, map[string]string{"Go": "go"})
+
+	if err := d.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	gauges := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"go repositories", testutil.ToFloat64(DatasetRepositories.WithLabelValues("go", "Go")), 2},
+		{"rust repositories", testutil.ToFloat64(DatasetRepositories.WithLabelValues(unknownLabel, "Rust")), 1},
+		{"unknown repositories", testutil.ToFloat64(DatasetRepositories.WithLabelValues(unknownLabel, unknownLabel)), 1},
+		{"enriched", testutil.ToFloat64(DatasetCompleteRatio.WithLabelValues(config.StageEnrich)), 0.5},
+		{"counted", testutil.ToFloat64(DatasetCompleteRatio.WithLabelValues(config.StageCount)), 0.5},
+		{"quality measures", testutil.ToFloat64(DatasetQualityMeasure.WithLabelValues("+Inf")), 2},
+		{"library ratio", testutil.ToFloat64(DatasetLibraryRatio), 1},
+	}
+
+	for _, g := range gauges {
+		if g.got != g.want {
+			t.Errorf("%s = %g, want %g", g.name, g.got, g.want)
+		}
+	}
+
+	// Languages, which are no longer in the database, are removed.
+	db.Where("primary_language = ?", "Rust").Delete(&models.Repository{})
+
+	if err := d.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(DatasetRepositories); n != 2 {
+		t.Errorf("series of the repositories = %d, want 2", n)
+	}
+}
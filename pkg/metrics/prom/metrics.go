@@ -0,0 +1,143 @@
+package prom
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Metrics of Glass, registered in a registry of their own, instead of the default registry of
+// Prometheus, which the libraries may register to. Every metric is listed in Definitions.
+
+const namespace = "glass"
+
+// APIs of the GraphQL requests.
+const (
+	ApiGitHub      = "github"
+	ApiSourceGraph = "sourcegraph"
+)
+
+// Registry of the metrics, which the metrics endpoint serves.
+var Registry = prometheus.NewRegistry()
+
+// Definition of a metric.
+type Definition struct {
+	Name    string
+	Help    string
+	Type    string
+	Labels  []string
+	Buckets []float64
+}
+
+// Types of the metrics.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Definitions of the metrics of Glass, in the order they are defined.
+var Definitions []Definition
+
+// Buckets of the durations of the commands and the requests, from 100ms to about 27 minutes.
+var durationBuckets = prometheus.ExponentialBuckets(0.1, 2, 15)
+
+// Buckets of the latencies of the API, from 5ms to about 40 seconds. Exports and fetches take long.
+var httpBuckets = prometheus.ExponentialBuckets(0.005, 2.5, 11)
+
+// Buckets of the sizes of the clones, from 64KB to 64GB.
+var sizeBuckets = prometheus.ExponentialBuckets(64<<10, 4, 11)
+
+var (
+	RepositoriesDiscovered = newCounterVec("repositories_discovered_total", "Repositories written to the database by the fetch stage.", "plugin")
+	RepositoriesEnriched   = newCounterVec("repositories_enriched_total", "Repositories, which metadata was written by the enrich stage.", "plugin")
+	RepositoriesMeasured   = newCounterVec("repositories_measured_total", "Repositories, which codebase size was written, by the stage: clone for the repository, count for its libraries.", "plugin", "stage")
+	RepositoryFailures     = newCounterVec("repository_failures_total", "Repositories, which failed in a stage, by the class of the error.", "plugin", "stage", "class")
+
+	GraphQlRequestDuration   = newHistogramVec("graphql_request_duration_seconds", "Latency of the GraphQL requests by the API and the status of the response, error when there was no response.", durationBuckets, "api", "status")
+	GitHubRateLimitRemaining = newGaugeVec("github_rate_limit_remaining", "Points left in the rate limit window of GitHub by the token, its position in GITHUB_API_TOKEN, by the latest response.", "token")
+	GitHubTokenRequests      = newCounterVec("github_token_requests_total", "Requests to GitHub by the token, its position in GITHUB_API_TOKEN.", "token")
+	GitHubRetries            = newCounterVec("github_retries_total", "Requests to GitHub, which were retried, by the reason: rate_limit, secondary_rate_limit, server_error or network.", "reason")
+	DiscoverySearches        = newCounterVec("discovery_searches_total", "Searches of the windows of the discovery sources by the source, sourcegraph or github, and the result: complete, split, when the window hit the limit or timed out, or truncated, when it couldn't be split further.", "source", "result")
+
+	CloneDuration   = newHistogram("clone_duration_seconds", "Duration of the git clones of the repositories.", durationBuckets)
+	CloneBytes      = newHistogram("clone_bytes", "Disk space of the cloned repositories.", sizeBuckets)
+	GoclocDuration  = newHistogram("gocloc_duration_seconds", "Duration of calculating the lines of code of a repository or a library.", durationBuckets)
+	ModuleDownloads = newCounterVec("module_downloads_total", "Libraries downloaded by the download stage, by the result: succeeded or failed.", "result")
+
+	JobQueueDepth = newGauge("job_queue_depth", "Jobs, which are queued, and not yet running.")
+
+	HttpRequests         = newCounterVec("http_requests_total", "Requests to the API by the route template, the method and the status.", "route", "method", "status")
+	HttpRequestDuration  = newHistogramVec("http_request_duration_seconds", "Latency of the requests to the API by the route template, the method and the status.", httpBuckets, "route", "method", "status")
+	HttpRequestsInFlight = newGaugeVec("http_requests_in_flight", "Requests to the API, which are being served, by the route template and the method.", "route", "method")
+
+	DatasetRepositories   = newGaugeVec("dataset_repositories", "Repositories in the database by the plugin and the primary language, unknown until the repository is enriched.", "plugin", "language")
+	DatasetCompleteRatio  = newGaugeVec("dataset_complete_ratio", "Share of the repositories, which have every column the stage writes, between 0 and 1.", "stage")
+	DatasetQualityMeasure = newGaugeVec("dataset_quality_measure_repositories", "Repositories, which Quality Measure is at most the upper bound le, cumulative like the buckets of a histogram.", "le")
+	DatasetLibraryRatio   = newGauge("dataset_library_ratio_median", "Median of the ratios of the library codebase size to the original codebase size of the measured repositories.")
+)
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+func newCounterVec(name string, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+
+	define(c, Definition{Name: name, Help: help, Type: TypeCounter, Labels: labels})
+
+	return c
+}
+
+func newGauge(name string, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help})
+
+	define(g, Definition{Name: name, Help: help, Type: TypeGauge})
+
+	return g
+}
+
+func newGaugeVec(name string, help string, labels ...string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, labels)
+
+	define(g, Definition{Name: name, Help: help, Type: TypeGauge, Labels: labels})
+
+	return g
+}
+
+func newHistogram(name string, help string, buckets []float64) prometheus.Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets})
+
+	define(h, Definition{Name: name, Help: help, Type: TypeHistogram, Buckets: buckets})
+
+	return h
+}
+
+func newHistogramVec(name string, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets}, labels)
+
+	define(h, Definition{Name: name, Help: help, Type: TypeHistogram, Labels: labels, Buckets: buckets})
+
+	return h
+}
+
+// Registers the metric, and adds its definition with the full name, e.g. glass_clone_bytes.
+func define(c prometheus.Collector, d Definition) {
+	Registry.MustRegister(c)
+
+	d.Name = prometheus.BuildFQName(namespace, "", d.Name)
+	Definitions = append(Definitions, d)
+}
+
+// ObserveGraphQl records the latency and the status of the GraphQL request to the API.
+func ObserveGraphQl(api string, res *http.Response, err error, duration time.Duration) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+	}
+
+	GraphQlRequestDuration.WithLabelValues(api, status).Observe(duration.Seconds())
+}
@@ -0,0 +1,37 @@
+package prom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Label of the requests, which don't match a route, so unknown paths don't create new series.
+const unmatchedRoute = "unmatched"
+
+// Middleware records the requests to the API by their route templates, e.g.
+// /api/glass/v1/repository/:id, instead of their paths.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		method := c.Request.Method
+
+		inFlight := HttpRequestsInFlight.WithLabelValues(route, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+
+		c.Next()
+
+		status := strconv.Itoa(c.Writer.Status())
+
+		HttpRequests.WithLabelValues(route, method, status).Inc()
+		HttpRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Natural keys of the tables, which identify the rows besides their ids, e.g. in the imports and
+// the creates, which report the rows, which exist already, as conflicts. Repositories are keyed on
+// the URL, since enrich replaces the repository_name with the short name of GitHub. Commits keep
+// the repository_name they were created with, which is validated to be "host/owner/name".
+var (
+	RepositoryKey = []string{"repository_url"}
+	CommitKey     = []string{"repository_name", "commit_date", "commit_user"}
+	SnapshotKey   = []string{"glass_version", "exported_at"}
+)
+
+// Columns returns the column names of a model, in the order the fields are declared in the struct.
+// Column names are read from the "json" -tags, which match the column names GORM creates.
+func Columns(model interface{}) []string {
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+
+	columns := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		if name := ColumnName(t.Field(i)); name != "" {
+			columns = append(columns, name)
+		}
+	}
+
+	return columns
+}
+
+// Values returns the values of the given columns of a model as strings, in the order of the columns.
+func Values(model interface{}, columns []string) []string {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	t := v.Type()
+
+	// Map of Column Name (as key) and index of the struct field.
+	fields := make(map[string]int, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		fields[ColumnName(t.Field(i))] = i
+	}
+
+	values := make([]string, len(columns))
+
+	for i, column := range columns {
+		if j, ok := fields[column]; ok {
+			values[i] = fmt.Sprint(v.Field(j).Interface())
+		}
+	}
+
+	return values
+}
+
+// Fields returns the values of the given columns of a model by column name, without converting them to strings.
+func Fields(model interface{}, columns []string) map[string]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	t := v.Type()
+
+	fields := make(map[string]interface{}, len(columns))
+
+	for i := 0; i < t.NumField(); i++ {
+		name := ColumnName(t.Field(i))
+
+		for _, column := range columns {
+			if column == name {
+				fields[name] = v.Field(i).Interface()
+			}
+		}
+	}
+
+	return fields
+}
+
+// SetValues parses the string values of the given columns to the fields of a model. It is the reverse of Values.
+func SetValues(model interface{}, columns []string, values []string) error {
+	v := reflect.ValueOf(model).Elem()
+	t := v.Type()
+
+	// Map of Column Name (as key) and index of the struct field.
+	fields := make(map[string]int, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		fields[ColumnName(t.Field(i))] = i
+	}
+
+	for i, column := range columns {
+		j, ok := fields[column]
+		if !ok || i >= len(values) {
+			continue
+		}
+
+		field := v.Field(j)
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(values[i])
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if values[i] == "" {
+				field.SetInt(0)
+				continue
+			}
+
+			n, err := strconv.ParseInt(values[i], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for column %s: %w", column, err)
+			}
+
+			field.SetInt(n)
+		default:
+			return fmt.Errorf("unsupported type of column %s: %s", column, field.Kind())
+		}
+	}
+
+	return nil
+}
+
+// ColumnName parses the column name from the "json" -tag of the struct field.
+func ColumnName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+
+	return tag
+}
@@ -5,69 +5,179 @@ type RepositoryResponse struct {
 }
 
 type Repository struct {
-	Id                   int    `json:"id" gorm:"primary_key"`
-	RepositoryName       string `json:"repository_name"`
-	RepositoryUrl        string `json:"repository_url"`
-	OpenIssueCount       string `json:"open_issue_count"`
-	ClosedIssueCount     string `json:"closed_issue_count"`
-	CommitCount          string `json:"commit_count"`
-	OriginalCodebaseSize string `json:"original_codebase_size"`
-	LibraryCodebaseSize  string `json:"library_codebase_size"`
-	RepositoryType       string `json:"repository_type"`
-	PrimaryLanguage      string `json:"primary_language"`
-	CreationDate         string `json:"creation_date"`
-	StargazerCount       string `json:"stargazer_count"`
-	LicenseInfo          string `json:"license_info"`
-	LatestRelease        string `json:"latest_release"`
+	Id                   int    `json:"id" gorm:"primary_key" parquet:"id"`
+	RepositoryName       string `json:"repository_name" parquet:"repository_name"`
+	RepositoryUrl        string `json:"repository_url" parquet:"repository_url"`
+	OpenIssueCount       string `json:"open_issue_count" parquet:"open_issue_count"`
+	ClosedIssueCount     string `json:"closed_issue_count" parquet:"closed_issue_count"`
+	CommitCount          string `json:"commit_count" parquet:"commit_count"`
+	OriginalCodebaseSize string `json:"original_codebase_size" parquet:"original_codebase_size"`
+	LibraryCodebaseSize  string `json:"library_codebase_size" parquet:"library_codebase_size"`
+	RepositoryType       string `json:"repository_type" parquet:"repository_type"`
+	PrimaryLanguage      string `json:"primary_language" parquet:"primary_language"`
+	CreationDate         string `json:"creation_date" parquet:"creation_date"`
+	StargazerCount       string `json:"stargazer_count" parquet:"stargazer_count"`
+	LicenseInfo          string `json:"license_info" parquet:"license_info"`
+	LatestRelease        string `json:"latest_release" parquet:"latest_release"`
+	DiscoverySource      string `json:"discovery_source" parquet:"discovery_source"`
 }
 
 type CreateRepositoryInput struct {
-	RepositoryName       string `json:"repository_name"`
-	RepositoryUrl        string `json:"repository_url"`
-	OpenIssueCount       string `json:"open_issue_count"`
-	ClosedIssueCount     string `json:"closed_issue_count"`
-	CommitCount          string `json:"commit_count"`
-	OriginalCodebaseSize string `json:"original_codebase_size"`
-	LibraryCodebaseSize  string `json:"library_codebase_size"`
+	RepositoryName       string `json:"repository_name" binding:"required,max=255"`
+	RepositoryUrl        string `json:"repository_url" binding:"required,repository_url"`
+	OpenIssueCount       string `json:"open_issue_count" binding:"count"`
+	ClosedIssueCount     string `json:"closed_issue_count" binding:"count"`
+	CommitCount          string `json:"commit_count" binding:"count"`
+	OriginalCodebaseSize string `json:"original_codebase_size" binding:"count"`
+	LibraryCodebaseSize  string `json:"library_codebase_size" binding:"count"`
 	RepositoryType       string `json:"repository_type"`
 	PrimaryLanguage      string `json:"primary_language"`
-	CreationDate         string `json:"creation_date"`
-	StargazerCount       string `json:"stargazer_count"`
+	CreationDate         string `json:"creation_date" binding:"date"`
+	StargazerCount       string `json:"stargazer_count" binding:"count"`
 	LicenseInfo          string `json:"license_info"`
-	LatestRelease        string `json:"latest_release"`
+	LatestRelease        string `json:"latest_release" binding:"date"`
+	DiscoverySource      string `json:"discovery_source" binding:"max=255"`
 }
 
 type UpdateRepositoryInput struct {
-	RepositoryName       string `json:"repository_name"`
-	RepositoryUrl        string `json:"repository_url"`
-	OpenIssueCount       string `json:"open_issue_count"`
-	ClosedIssueCount     string `json:"closed_issue_count"`
-	CommitCount          string `json:"commit_count"`
-	OriginalCodebaseSize string `json:"original_codebase_size"`
-	LibraryCodebaseSize  string `json:"library_codebase_size"`
+	RepositoryName       string `json:"repository_name" binding:"max=255"`
+	RepositoryUrl        string `json:"repository_url" binding:"repository_url"`
+	OpenIssueCount       string `json:"open_issue_count" binding:"count"`
+	ClosedIssueCount     string `json:"closed_issue_count" binding:"count"`
+	CommitCount          string `json:"commit_count" binding:"count"`
+	OriginalCodebaseSize string `json:"original_codebase_size" binding:"count"`
+	LibraryCodebaseSize  string `json:"library_codebase_size" binding:"count"`
 	RepositoryType       string `json:"repository_type"`
 	PrimaryLanguage      string `json:"primary_language"`
-	CreationDate         string `json:"creation_date"`
-	StargazerCount       string `json:"stargazer_count"`
+	CreationDate         string `json:"creation_date" binding:"date"`
+	StargazerCount       string `json:"stargazer_count" binding:"count"`
 	LicenseInfo          string `json:"license_info"`
-	LatestRelease        string `json:"latest_release"`
+	LatestRelease        string `json:"latest_release" binding:"date"`
+	DiscoverySource      string `json:"discovery_source" binding:"max=255"`
 }
 
 type Commit struct {
-	Id             int    `json:"id" gorm:"primary_key"`
-	RepositoryName string `json:"repository_name"`
-	CommitDate     string `json:"commit_date"`
-	CommitUser     string `json:"commit_user"`
+	Id             int    `json:"id" gorm:"primary_key" parquet:"id"`
+	RepositoryName string `json:"repository_name" parquet:"repository_name"`
+	CommitDate     string `json:"commit_date" parquet:"commit_date"`
+	CommitUser     string `json:"commit_user" parquet:"commit_user"`
 }
 
 type CreateCommitInput struct {
-	RepositoryName string `json:"repository_name"`
-	CommitDate     string `json:"commit_date"`
+	RepositoryName string `json:"repository_name" binding:"required,repository_url"`
+	CommitDate     string `json:"commit_date" binding:"date"`
 	CommitUser     string `json:"commit_user"`
 }
 
 type UpdateCommitInput struct {
-	RepositoryName string `json:"repository_name"`
-	CommitDate     string `json:"commit_date"`
+	RepositoryName string `json:"repository_name" binding:"repository_url"`
+	CommitDate     string `json:"commit_date" binding:"date"`
 	CommitUser     string `json:"commit_user"`
 }
+
+type Snapshot struct {
+	Id                    int    `json:"id" gorm:"primary_key" parquet:"id"`
+	GlassVersion          string `json:"glass_version" parquet:"glass_version"`
+	QualityMeasureVersion string `json:"quality_measure_version" parquet:"quality_measure_version"`
+	Format                string `json:"format" parquet:"format"`
+	ExportedAt            string `json:"exported_at" parquet:"exported_at"`
+	ImportedAt            string `json:"imported_at" parquet:"imported_at"`
+	RepositoryCount       int    `json:"repository_count" parquet:"repository_count"`
+	CommitCount           int    `json:"commit_count" parquet:"commit_count"`
+}
+
+type Job struct {
+	Id     int    `json:"id" gorm:"primary_key"`
+	Plugin string `json:"plugin"`
+	Count  int    `json:"count"`
+
+	// Retries run only the stage for the repositories, instead of every stage.
+	Stage        string   `json:"stage"`
+	Repositories []string `json:"repositories" gorm:"serializer:json"`
+
+	// Sources, which the fetch stage discovers the repositories from.
+	Sources []Source `json:"sources" gorm:"serializer:json"`
+
+	// Stage settings of the request, and the settings the job runs with.
+	Overrides map[string]string `json:"overrides" gorm:"serializer:json"`
+	Stages    map[string]Stage  `json:"stages" gorm:"serializer:json"`
+
+	Status     string `json:"status"`
+	Error      string `json:"error"`
+	CreatedAt  string `json:"created_at"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// Settings of a stage of a job by the name of the stage, see config.Stage. Timeout is a duration
+// string, e.g. "10m0s".
+type Stage struct {
+	Workers    int    `json:"workers"`
+	Timeout    string `json:"timeout"`
+	BatchSize  int    `json:"batch_size"`
+	DiskBudget int64  `json:"disk_budget"`
+}
+
+// Output of a command, which a stage of a job ran for a repository, e.g. git clone.
+type JobLog struct {
+	Id             int    `json:"id" gorm:"primary_key"`
+	JobId          int    `json:"job_id" gorm:"index"`
+	RepositoryName string `json:"repository_name"`
+	Stage          string `json:"stage"`
+	Command        string `json:"command"`
+	Output         string `json:"output"`
+	Error          string `json:"error"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type CreateJobInput struct {
+	Plugin    string            `json:"plugin" binding:"required"`
+	Count     int               `json:"count" binding:"required,min=1"`
+	Overrides map[string]string `json:"overrides"`
+	Sources   []Source          `json:"sources" binding:"dive"`
+}
+
+// Types of the sources.
+const (
+	SourceTypeSourceGraph = "sourcegraph"
+	SourceTypeGitHub      = "github"
+	SourceTypeSeeds       = "seeds"
+)
+
+// Source of the repositories, which the fetch stage discovers: a search of SourceGraph or GitHub,
+// or a seed list of repositories. The repositories are tagged with the tag of the source, so a
+// dataset can be built from many sampling strategies.
+type Source struct {
+	Type string `json:"type" binding:"required,oneof=sourcegraph github seeds"`
+
+	// Search query of SourceGraph, e.g. "lang:go select:repo repohasfile:go.mod", or of GitHub,
+	// e.g. "language:go stars:>100 topic:cli pushed:>2023-01-01".
+	Query string `json:"query" binding:"required_unless=Type seeds"`
+
+	// Repositories of a seed list, e.g. "github.com/owner/name" or "https://github.com/owner/name".
+	Repositories []string `json:"repositories" binding:"required_if=Type seeds,dive,seed"`
+
+	// Tag of the repositories, which the source discovers. Defaults to the type.
+	Tag string `json:"tag" binding:"max=255"`
+}
+
+// Label returns the tag of the source, or the type, when it has no tag.
+func (s Source) Label() string {
+	if s.Tag != "" {
+		return s.Tag
+	}
+
+	return s.Type
+}
+
+type Failure struct {
+	Id             int    `json:"id" gorm:"primary_key"`
+	Plugin         string `json:"plugin"`
+	RepositoryName string `json:"repository_name"`
+	Stage          string `json:"stage"`
+	Class          string `json:"class"`
+	Message        string `json:"message"`
+	Attempts       int    `json:"attempts"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
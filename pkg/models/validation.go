@@ -0,0 +1,120 @@
+package models
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Validation rules used in the "binding" -tags of the input structs.
+//
+//	count:          empty, or a non-negative integer. Counts are stored as strings.
+//	date:           empty, or an RFC 3339 timestamp (GitHub), a YYYY-MM-DD date or a git default date.
+//	repository_url: empty, or "host/owner/name" as returned by SourceGraph, without a scheme or a
+//	                ".git" suffix, which the stages add to clone the repository.
+//	seed:           a repository of a seed list, "host/owner/name" or an http(s) URL of it, see
+//	                SeedRepository.
+
+var countPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// Formats accepted by the "date" -rule.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+	"Mon Jan 2 15:04:05 2006 -0700",
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report the fields with their JSON names.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return ColumnName(field)
+	})
+
+	v.RegisterValidation("count", validateCount)
+	v.RegisterValidation("date", validateDate)
+	v.RegisterValidation("repository_url", validateRepositoryUrl)
+	v.RegisterValidation("seed", validateSeed)
+}
+
+func validateCount(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+
+	return value == "" || countPattern.MatchString(value)
+}
+
+func validateDate(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if value == "" {
+		return true
+	}
+
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateRepositoryUrl(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+
+	return value == "" || isRepositoryUrl(value)
+}
+
+func validateSeed(fl validator.FieldLevel) bool {
+	_, ok := SeedRepository(fl.Field().String())
+
+	return ok
+}
+
+// SeedRepository parses a repository of a seed list to its URL: "github.com/owner/name",
+// "https://github.com/owner/name.git" and "HTTP://GitHub.com/owner/name/" are "github.com/owner/name".
+func SeedRepository(entry string) (string, bool) {
+	entry = strings.TrimSpace(entry)
+
+	if scheme, rest, ok := strings.Cut(entry, "://"); ok {
+		if scheme = strings.ToLower(scheme); scheme != "http" && scheme != "https" {
+			return "", false
+		}
+
+		entry = rest
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(entry, "/"), ".git"), "/")
+	if len(parts) == 3 {
+		parts[0] = strings.ToLower(parts[0])
+	}
+
+	if url := strings.Join(parts, "/"); isRepositoryUrl(url) {
+		return url, true
+	}
+
+	return "", false
+}
+
+// Reports whether the value is "host/owner/name", e.g. "github.com/owner/name".
+func isRepositoryUrl(value string) bool {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 || !strings.Contains(parts[0], ".") || strings.HasSuffix(value, ".git") {
+		return false
+	}
+
+	for _, part := range parts {
+		if part == "" || strings.ContainsAny(part, ": ") {
+			return false
+		}
+	}
+
+	return true
+}
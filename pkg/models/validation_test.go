@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestValidateRepositoryUrl(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"", true},
+		{"github.com/owner/name", true},
+		{"gitlab.example.com/owner/name.js", true},
+		{"https://github.com/owner/name", false},
+		{"http://github.com/owner/name", false},
+		{"github.com/owner/name.git", false},
+		{"github.com/owner/name/tree/main", false},
+		{"github.com/owner/name/", false},
+		{"github.com/owner", false},
+		{"owner/name", false},
+		{"github.com//name", false},
+	}
+
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(&UpdateRepositoryInput{RepositoryUrl: tt.url})
+		if got := err == nil; got != tt.want {
+			t.Errorf("repository_url %q valid = %v, want %v: %v", tt.url, got, tt.want, err)
+		}
+	}
+}
+
+func TestSeedRepository(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  string
+		ok    bool
+	}{
+		{"github.com/owner/name", "github.com/owner/name", true},
+		{"https://GitHub.com/owner/name.git", "github.com/owner/name", true},
+		{"HTTP://github.com/owner/name/", "github.com/owner/name", true},
+		{"ssh://github.com/owner/name", "", false},
+		{"https://github.com/owner/name/tree/main", "", false},
+		{"github.com/owner", "", false},
+		{"owner/name", "", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := SeedRepository(tt.entry); got != tt.want || ok != tt.ok {
+			t.Errorf("SeedRepository(%q) = %q, %v, want %q, %v", tt.entry, got, ok, tt.want, tt.ok)
+		}
+
+		err := binding.Validator.ValidateStruct(&Source{Type: SourceTypeSeeds, Repositories: []string{tt.entry}})
+		if (err == nil) != tt.ok {
+			t.Errorf("seed %q error = %v, want valid %v", tt.entry, err, tt.ok)
+		}
+	}
+}
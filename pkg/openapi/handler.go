@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui/index.html
+var ui []byte
+
+// Handler serves the specification as JSON.
+func Handler(c *gin.Context) {
+	c.JSON(http.StatusOK, NewDocument())
+}
+
+// UIHandler serves the interactive documentation, which renders the specification.
+func UIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", ui)
+}
+
+// Validate compares the routes registered to the router under the BasePath with the route table,
+// and returns an error listing the routes, which are missing from either of them.
+func Validate(registered gin.RoutesInfo) error {
+	specified := make(map[string]bool, len(routes))
+
+	for _, r := range routes {
+		specified[r.Method+" "+BasePath+r.Path] = true
+	}
+
+	var undocumented, unregistered []string
+
+	for _, r := range registered {
+		if !strings.HasPrefix(r.Path, BasePath+"/") {
+			continue
+		}
+
+		key := r.Method + " " + r.Path
+
+		if !specified[key] {
+			undocumented = append(undocumented, key)
+		}
+
+		delete(specified, key)
+	}
+
+	for key := range specified {
+		unregistered = append(unregistered, key)
+	}
+
+	if len(undocumented) == 0 && len(unregistered) == 0 {
+		return nil
+	}
+
+	sort.Strings(undocumented)
+	sort.Strings(unregistered)
+
+	return fmt.Errorf("openapi specification doesn't match the router: routes missing from the specification: %v, routes missing from the router: %v", undocumented, unregistered)
+}
+
+// Converts the gin path parameters to the OpenAPI syntax, e.g. "/repository/:id" to "/repository/{id}".
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func lowerMethod(method string) string {
+	return strings.ToLower(method)
+}
@@ -0,0 +1,135 @@
+package openapi
+
+import (
+	"github.com/haapjari/glass/pkg/version"
+)
+
+// OpenAPI 3 description of the Glass API. The document is built from the route table in routes.go,
+// and the schemas are generated from the Go types, so the specification follows the models.
+
+const (
+	BasePath = "/api/glass/v1"
+
+	// Version of the OpenAPI Specification the document conforms to.
+	SpecVersion = "3.0.3"
+)
+
+type Document struct {
+	OpenApi    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers"`
+	Tags       []Tag                `json:"tags"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	Url string `json:"url"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PathItem holds the operations of a path by lowercase HTTP method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string             `json:"tags"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description,omitempty"`
+	OperationId string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// NewDocument builds the specification of every route in the route table.
+func NewDocument() *Document {
+	d := new(Document)
+
+	d.OpenApi = SpecVersion
+	d.Info = Info{
+		Title:       "Glass",
+		Description: "Collects the metadata of open-source repositories, and measures their quality.",
+		Version:     version.Version,
+	}
+	d.Servers = []Server{{Url: BasePath}}
+	d.Tags = tags
+	d.Paths = make(map[string]*PathItem)
+
+	s := newSchemas()
+
+	for _, r := range routes {
+		path := specPath(r.Path)
+
+		item, ok := d.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			d.Paths[path] = item
+		}
+
+		(*item)[lowerMethod(r.Method)] = r.operation(s)
+	}
+
+	d.Components = Components{
+		Schemas:   s.components,
+		Responses: errorResponses(s),
+	}
+
+	return d
+}
@@ -0,0 +1,429 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/bulk"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/importer"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/plugins"
+	"github.com/haapjari/glass/pkg/query"
+)
+
+// Route table of the API. Paths are relative to the BasePath, and use the syntax of gin.
+// Every route registered to the router needs an entry here, see Validate.
+
+type route struct {
+	Method      string
+	Path        string
+	Id          string
+	Tag         string
+	Summary     string
+	Description string
+	Parameters  []Parameter
+	Body        *body
+	Response    *body
+	Errors      []int
+}
+
+// Content of a request or a response.
+type body struct {
+	ContentType string
+	Description string
+	Schema      func(s *schemas) *Schema
+}
+
+var tags = []Tag{
+	{Name: "repository", Description: "Repositories of the dataset, and their metadata."},
+	{Name: "commit", Description: "Commits of the repositories."},
+	{Name: "job", Description: "Background jobs, which collect repositories with the plugins."},
+	{Name: "failure", Description: "Repositories, which failed in a stage of a plugin."},
+	{Name: "dataset", Description: "Export and import of the whole dataset."},
+	{Name: "meta", Description: "Specification and metrics of the service."},
+}
+
+var routes = []route{
+	{Method: http.MethodGet, Path: "/repository", Id: "listRepositories", Tag: "repository",
+		Summary:    "List repositories",
+		Parameters: listParameters("created_before, created_after, released_before and released_after filter the creation and latest release dates."),
+		Response:   jsonBody("Page of repositories.", list(models.Repository{})),
+		Errors:     []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/repository", Id: "createRepository", Tag: "repository",
+		Summary:  "Create a repository",
+		Body:     jsonBody("Repository to create.", ref(models.CreateRepositoryInput{})),
+		Response: jsonBody("Created repository.", data(models.Repository{})),
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity}},
+	{Method: http.MethodGet, Path: "/repository/:id", Id: "getRepository", Tag: "repository",
+		Summary:  "Get a repository",
+		Response: jsonBody("Repository.", data(models.Repository{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodDelete, Path: "/repository/:id", Id: "deleteRepository", Tag: "repository",
+		Summary:  "Delete a repository",
+		Response: jsonBody("Deleted repository.", succeed(models.Repository{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/repository/:id", Id: "updateRepository", Tag: "repository",
+		Summary:     "Update a repository",
+		Description: "Only the non-empty fields of the body are updated.",
+		Body:        jsonBody("Fields to update.", ref(models.UpdateRepositoryInput{})),
+		Response:    jsonBody("Updated repository.", data(models.Repository{})),
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity}},
+	{Method: http.MethodPost, Path: "/repository/bulk", Id: "bulkCreateRepositories", Tag: "repository",
+		Summary:     "Create repositories in bulk",
+		Description: bulkDescription + bulkCreateDescription,
+		Body:        bulkBody(models.CreateRepositoryInput{}),
+		Response:    jsonBody("Result of every item.", dataList(bulk.Result{}, "BulkResult")),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodPatch, Path: "/repository/bulk", Id: "bulkUpdateRepositories", Tag: "repository",
+		Summary:     "Update repositories in bulk",
+		Description: bulkDescription + " Items require an id, and only the fields present in the item are updated.",
+		Body:        bulkBody(models.UpdateRepositoryInput{}),
+		Response:    jsonBody("Result of every item.", dataList(bulk.Result{}, "BulkResult")),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodDelete, Path: "/repository/bulk", Id: "bulkDeleteRepositories", Tag: "repository",
+		Summary:    "Delete repositories in bulk",
+		Parameters: bulkDeleteParameters,
+		Response:   jsonBody("Amount of deleted rows.", deleted),
+		Errors:     []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/repository/fetch", Id: "fetchRepositories", Tag: "repository",
+		Summary:     "Fetch repositories",
+		Description: "Searches repositories from SourceGraph, and collects their metadata with the plugin of the type.",
+		Parameters: []Parameter{
+			{Name: "count", In: "query", Required: true, Description: "Amount of repositories to fetch.", Schema: &Schema{Type: "integer", Minimum: intPtr(1)}},
+			{Name: "type", In: "query", Required: true, Description: "Plugin, which collects the metadata.", Schema: &Schema{Type: "string", Enum: plugins.Names}},
+		},
+		Response: &body{Description: "Repositories were fetched."},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodGet, Path: "/repository/csv", Id: "exportRepositoriesCsv", Tag: "repository",
+		Summary:    "Export repositories as CSV",
+		Parameters: csvParameters,
+		Response:   &body{ContentType: "text/csv", Description: "Repositories as RFC 4180 CSV.", Schema: stringSchema},
+		Errors:     []int{http.StatusBadRequest}},
+
+	{Method: http.MethodGet, Path: "/commit", Id: "listCommits", Tag: "commit",
+		Summary:    "List commits",
+		Parameters: listParameters("committed_before and committed_after filter the commit date."),
+		Response:   jsonBody("Page of commits.", list(models.Commit{})),
+		Errors:     []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/commit", Id: "createCommit", Tag: "commit",
+		Summary:  "Create a commit",
+		Body:     jsonBody("Commit to create.", ref(models.CreateCommitInput{})),
+		Response: jsonBody("Created commit.", data(models.Commit{})),
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity}},
+	{Method: http.MethodGet, Path: "/commit/:id", Id: "getCommit", Tag: "commit",
+		Summary:  "Get a commit",
+		Response: jsonBody("Commit.", data(models.Commit{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodDelete, Path: "/commit/:id", Id: "deleteCommit", Tag: "commit",
+		Summary:  "Delete a commit",
+		Response: jsonBody("Deleted commit.", succeed(models.Commit{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/commit/:id", Id: "updateCommit", Tag: "commit",
+		Summary:     "Update a commit",
+		Description: "Only the non-empty fields of the body are updated.",
+		Body:        jsonBody("Fields to update.", ref(models.UpdateCommitInput{})),
+		Response:    jsonBody("Updated commit.", data(models.Commit{})),
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity}},
+	{Method: http.MethodGet, Path: "/commit/csv", Id: "exportCommitsCsv", Tag: "commit",
+		Summary:    "Export commits as CSV",
+		Parameters: csvParameters,
+		Response:   &body{ContentType: "text/csv", Description: "Commits as RFC 4180 CSV.", Schema: stringSchema},
+		Errors:     []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/commit/bulk", Id: "bulkCreateCommits", Tag: "commit",
+		Summary:     "Create commits in bulk",
+		Description: bulkDescription + bulkCreateDescription,
+		Body:        bulkBody(models.CreateCommitInput{}),
+		Response:    jsonBody("Result of every item.", dataList(bulk.Result{}, "BulkResult")),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodPatch, Path: "/commit/bulk", Id: "bulkUpdateCommits", Tag: "commit",
+		Summary:     "Update commits in bulk",
+		Description: bulkDescription + " Items require an id, and only the fields present in the item are updated.",
+		Body:        bulkBody(models.UpdateCommitInput{}),
+		Response:    jsonBody("Result of every item.", dataList(bulk.Result{}, "BulkResult")),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodDelete, Path: "/commit/bulk", Id: "bulkDeleteCommits", Tag: "commit",
+		Summary:    "Delete commits in bulk",
+		Parameters: bulkDeleteParameters,
+		Response:   jsonBody("Amount of deleted rows.", deleted),
+		Errors:     []int{http.StatusBadRequest}},
+
+	{Method: http.MethodGet, Path: "/job", Id: "listJobs", Tag: "job",
+		Summary:    "List jobs",
+		Parameters: listParameters("created_before and created_after filter the creation time."),
+		Response:   jsonBody("Page of jobs.", list(models.Job{})),
+		Errors:     []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/job", Id: "createJob", Tag: "job",
+		Summary:     "Queue a job",
+		Description: "Queues a job, which runs every stage of the plugin: " + strings.Join(config.Stages, ", ") + ". Jobs run one at a time, in the order they were queued. " + overridesDescription + " " + sourcesDescription,
+		Body:        jsonBody("Job to queue.", ref(models.CreateJobInput{})),
+		Response:    jsonBody("Queued job.", data(models.Job{})),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	{Method: http.MethodGet, Path: "/job/:id", Id: "getJob", Tag: "job",
+		Summary:  "Get a job",
+		Response: jsonBody("Job, and the stage settings it runs with.", data(models.Job{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/job/:id/logs", Id: "listJobLogs", Tag: "job",
+		Summary:     "List the logs of a job",
+		Description: "Output of the commands, which the stages of the job ran for the repositories, e.g. git clone and go get. Filter by repository_name and stage.",
+		Parameters:  listParameters("created_before and created_after filter the time the command finished."),
+		Response:    jsonBody("Page of logs.", list(models.JobLog{})),
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	{Method: http.MethodGet, Path: "/failures", Id: "listFailures", Tag: "failure",
+		Summary:     "List failures",
+		Description: "A repository, which fails in a stage, is recorded with the class of the error, and the stage continues with the other repositories. Classes are " + strings.Join(failures.Classes, ", ") + ". The failure is deleted, when the stage succeeds for the repository.",
+		Parameters:  listParameters("created_before, created_after, updated_before and updated_after filter the time of the first and the latest attempt."),
+		Response:    jsonBody("Page of failures.", list(models.Failure{})),
+		Errors:      []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/failures/:id", Id: "getFailure", Tag: "failure",
+		Summary:  "Get a failure",
+		Response: jsonBody("Failure.", data(models.Failure{})),
+		Errors:   []int{http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/failures/:id/retry", Id: "retryFailure", Tag: "failure",
+		Summary:     "Retry a failure",
+		Description: "Queues a job, which runs the stage of the failure again for its repository.",
+		Response:    jsonBody("Queued job.", data(models.Job{})),
+		Errors:      []int{http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/failures/retry", Id: "retryFailures", Tag: "failure",
+		Summary:     "Retry failures",
+		Description: "Queues a job for every plugin and stage of the failures, which match the filters, e.g. ?class=timeout&stage=clone. Without filters, every failure is retried.",
+		Parameters:  []Parameter{filterParameter("created_before, created_after, updated_before and updated_after filter the time of the first and the latest attempt.")},
+		Response:    jsonBody("Queued jobs.", dataList(models.Job{})),
+		Errors:      []int{http.StatusBadRequest}},
+
+	{Method: http.MethodGet, Path: "/export", Id: "exportDataset", Tag: "dataset",
+		Summary:     "Export the dataset",
+		Description: "Returns a tarball of every table, a data dictionary (schema.json) and a manifest (manifest.json).",
+		Parameters: []Parameter{
+			{Name: "format", In: "query", Description: "Format of the tables.", Schema: &Schema{Type: "string", Enum: []string{"parquet", "jsonl", "csv"}}},
+		},
+		Response: &body{ContentType: "application/gzip", Description: "Dataset bundle as .tar.gz.", Schema: binarySchema},
+		Errors:   []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/import", Id: "importDataset", Tag: "dataset",
+		Summary:     "Import a dataset bundle",
+		Description: "Merges a bundle created by the export to the database. Rows are matched by their natural keys.",
+		Parameters: []Parameter{
+			{Name: "dry_run", In: "query", Description: "Report the changes without writing them.", Schema: &Schema{Type: "boolean"}},
+			{Name: "on_conflict", In: "query", Description: "Resolution of the columns, which differ in the database and in the bundle.", Schema: &Schema{Type: "string", Enum: []string{importer.OnConflictSkip, importer.OnConflictOverwrite}}},
+		},
+		Body:     &body{ContentType: "application/gzip", Description: "Dataset bundle as .tar.gz. Also accepted as the file field of a multipart form.", Schema: binarySchema},
+		Response: jsonBody("Import report.", data(importer.Report{}, "ImportReport")),
+		Errors:   []int{http.StatusBadRequest}},
+
+	{Method: http.MethodGet, Path: "/openapi.json", Id: "getOpenApi", Tag: "meta",
+		Summary:  "OpenAPI specification",
+		Response: &body{ContentType: "application/json", Description: "This document.", Schema: objectSchema}},
+	{Method: http.MethodGet, Path: "/docs", Id: "getDocs", Tag: "meta",
+		Summary:  "API documentation",
+		Response: &body{ContentType: "text/html", Description: "Interactive documentation of the specification.", Schema: stringSchema}},
+	{Method: http.MethodGet, Path: "/metrics", Id: "getMetrics", Tag: "meta",
+		Summary:  "Prometheus metrics",
+		Response: &body{ContentType: "text/plain", Description: "Metrics in the Prometheus exposition format.", Schema: stringSchema}},
+}
+
+var sourcesDescription = "sources lists the sources the fetch stage discovers count new repositories from, each tagged with the tag of the source in discovery_source, e.g. [{\"type\": \"github\", \"query\": \"language:go stars:>100\", \"tag\": \"popular\"}, {\"type\": \"seeds\", \"repositories\": [\"github.com/owner/name\"], \"tag\": \"seeds\"}]. Types are sourcegraph and github, which search the query, and seeds, which lists the repositories. Without sources, the default SourceGraph search of the plugin is used."
+
+var overridesDescription = "overrides sets the stage settings of the job, e.g. {\"" + config.StageKey(config.StageEnrich, config.SettingWorkers) + "\": \"5\", \"" + config.StageKey(config.StageDownload, config.SettingDiskBudget) + "\": \"20GB\"}. Keys are " + config.StageKey("<stage>", "<SETTING>") + ", where the setting is WORKERS, TIMEOUT (e.g. 30s), BATCH_SIZE or DISK_BUDGET (e.g. 500MB)."
+
+const bulkDescription = "Accepts a JSON array, or newline-delimited JSON objects. Every item is validated before anything is written, in a single transaction."
+
+const bulkCreateDescription = " Items, which have the natural key of an existing row, or of another item, are conflicts, and nothing is written."
+
+var csvParameters = []Parameter{
+	{Name: "columns", In: "query", Description: "Comma-separated columns of the CSV. Defaults to every column.", Schema: &Schema{Type: "string"}},
+	filterParameter(""),
+}
+
+var bulkDeleteParameters = []Parameter{
+	{Name: "all", In: "query", Description: "Required to delete every row, when there are no filters.", Schema: &Schema{Type: "boolean"}},
+	filterParameter(""),
+}
+
+func listParameters(ranges string) []Parameter {
+	return []Parameter{
+		{Name: "limit", In: "query", Description: "Maximum amount of rows.", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Maximum: intPtr(query.MaxLimit)}},
+		{Name: "offset", In: "query", Description: "Amount of rows to skip.", Schema: &Schema{Type: "integer", Minimum: intPtr(0)}},
+		{Name: "cursor", In: "query", Description: "next_cursor of the previous page. Stays stable while rows are added.", Schema: &Schema{Type: "string"}},
+		{Name: "sort", In: "query", Description: "Comma-separated columns. - prefix sorts descending, e.g. -stargazer_count,repository_name.", Schema: &Schema{Type: "string"}},
+		{Name: "fields", In: "query", Description: "Comma-separated columns of the returned rows.", Schema: &Schema{Type: "string"}},
+		filterParameter(ranges),
+	}
+}
+
+// Filters are free-form query parameters, named after the columns.
+func filterParameter(ranges string) Parameter {
+	explode := true
+
+	description := "Filters by column: column=value (repeat for any of the values), column>=value, column<=value, column>value, column<value, column!=value. Counts are compared as integers."
+	if ranges != "" {
+		description += " " + ranges
+	}
+
+	return Parameter{
+		Name:        "filter",
+		In:          "query",
+		Description: description,
+		Style:       "form",
+		Explode:     &explode,
+		Schema:      &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+	}
+}
+
+func (r route) operation(s *schemas) *Operation {
+	o := &Operation{
+		Tags:        []string{r.Tag},
+		Summary:     r.Summary,
+		Description: r.Description,
+		OperationId: r.Id,
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, segment := range strings.Split(r.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			o.Parameters = append(o.Parameters, Parameter{Name: segment[1:], In: "path", Required: true, Schema: &Schema{Type: "integer"}})
+		}
+	}
+
+	o.Parameters = append(o.Parameters, r.Parameters...)
+
+	if r.Body != nil {
+		o.RequestBody = &RequestBody{Description: r.Body.Description, Required: true, Content: r.Body.content(s)}
+	}
+
+	o.Responses["200"] = &Response{Description: r.Response.Description, Content: r.Response.content(s)}
+
+	for _, status := range append(r.Errors, http.StatusInternalServerError) {
+		o.Responses[strconv.Itoa(status)] = &Response{Ref: "#/components/responses/" + errorNames[status]}
+	}
+
+	return o
+}
+
+func (b *body) content(s *schemas) map[string]MediaType {
+	if b.Schema == nil {
+		return nil
+	}
+
+	return map[string]MediaType{b.ContentType: {Schema: b.Schema(s)}}
+}
+
+// Component names of the error responses by status.
+var errorNames = map[int]string{
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusNotFound:            "NotFound",
+	http.StatusConflict:            "Conflict",
+	http.StatusUnprocessableEntity: "ValidationFailed",
+	http.StatusInternalServerError: "InternalError",
+}
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:          "Malformed request (" + apierror.CodeBadRequest + ").",
+	http.StatusNotFound:            "Row was not found (" + apierror.CodeNotFound + ").",
+	http.StatusConflict:            "Row with the same natural key exists (" + apierror.CodeConflict + ").",
+	http.StatusUnprocessableEntity: "Fields failed validation (" + apierror.CodeValidation + ").",
+	http.StatusInternalServerError: "Unexpected error (" + apierror.CodeInternal + ").",
+}
+
+// Every error is returned in the envelope of apierror.
+func errorResponses(s *schemas) map[string]*Response {
+	responses := make(map[string]*Response, len(errorNames))
+
+	envelope := &Schema{
+		Type:       "object",
+		Required:   []string{"error"},
+		Properties: map[string]*Schema{"error": s.ref(apierror.Error{})},
+	}
+
+	for status, name := range errorNames {
+		responses[name] = &Response{
+			Description: errorDescriptions[status],
+			Content:     map[string]MediaType{"application/json": {Schema: envelope}},
+		}
+	}
+
+	return responses
+}
+
+func jsonBody(description string, schema func(s *schemas) *Schema) *body {
+	return &body{ContentType: "application/json", Description: description, Schema: schema}
+}
+
+func bulkBody(v interface{}) *body {
+	return &body{
+		ContentType: "application/json",
+		Description: "Items as a JSON array. Newline-delimited JSON (application/x-ndjson) is accepted as well.",
+		Schema: func(s *schemas) *Schema {
+			return &Schema{Type: "array", Items: s.ref(v)}
+		},
+	}
+}
+
+func ref(v interface{}, name ...string) func(s *schemas) *Schema {
+	return func(s *schemas) *Schema {
+		return s.ref(v, name...)
+	}
+}
+
+// {"data": v}
+func data(v interface{}, name ...string) func(s *schemas) *Schema {
+	return func(s *schemas) *Schema {
+		return envelope("data", s.ref(v, name...))
+	}
+}
+
+// {"data": [v]}
+func dataList(v interface{}, name ...string) func(s *schemas) *Schema {
+	return func(s *schemas) *Schema {
+		return envelope("data", &Schema{Type: "array", Items: s.ref(v, name...)})
+	}
+}
+
+// {"succeed": v}
+func succeed(v interface{}) func(s *schemas) *Schema {
+	return func(s *schemas) *Schema {
+		return envelope("succeed", s.ref(v))
+	}
+}
+
+// {"data": [v], "meta": {...}, "links": {...}}
+func list(v interface{}) func(s *schemas) *Schema {
+	return func(s *schemas) *Schema {
+		return &Schema{
+			Type:     "object",
+			Required: []string{"data", "meta", "links"},
+			Properties: map[string]*Schema{
+				"data":  {Type: "array", Items: s.ref(v)},
+				"meta":  s.ref(query.Meta{}),
+				"links": s.ref(query.Links{}),
+			},
+		}
+	}
+}
+
+func deleted(s *schemas) *Schema {
+	return envelope("data", &Schema{Type: "object", Properties: map[string]*Schema{"deleted": {Type: "integer"}}})
+}
+
+func envelope(key string, schema *Schema) *Schema {
+	return &Schema{Type: "object", Required: []string{key}, Properties: map[string]*Schema{key: schema}}
+}
+
+func stringSchema(s *schemas) *Schema {
+	return &Schema{Type: "string"}
+}
+
+func binarySchema(s *schemas) *Schema {
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+func objectSchema(s *schemas) *Schema {
+	return &Schema{Type: "object"}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+// Descriptions of the validation rules registered in pkg/models.
+var ruleDescriptions = map[string]string{
+	"count":          "Non-negative integer stored as a string.",
+	"date":           "RFC 3339 timestamp, YYYY-MM-DD date or git date.",
+	"repository_url": "Repository URL without a scheme, e.g. github.com/owner/name.",
+	"seed":           "Repository, e.g. github.com/owner/name or https://github.com/owner/name.",
+}
+
+// Tables of the data dictionary, which describe the columns of the models.
+var modelTables = map[reflect.Type]string{
+	reflect.TypeOf(models.Repository{}):            "repositories",
+	reflect.TypeOf(models.CreateRepositoryInput{}): "repositories",
+	reflect.TypeOf(models.UpdateRepositoryInput{}): "repositories",
+	reflect.TypeOf(models.Commit{}):                "commits",
+	reflect.TypeOf(models.CreateCommitInput{}):     "commits",
+	reflect.TypeOf(models.UpdateCommitInput{}):     "commits",
+	reflect.TypeOf(models.Snapshot{}):              "snapshots",
+}
+
+// Generates the schemas of Go types, and collects the schemas of structs to the components.
+type schemas struct {
+	components   map[string]*Schema
+	names        map[reflect.Type]string
+	descriptions map[string]map[string]string
+}
+
+func newSchemas() *schemas {
+	s := new(schemas)
+
+	s.components = make(map[string]*Schema)
+	s.names = make(map[reflect.Type]string)
+	s.descriptions = make(map[string]map[string]string)
+
+	for _, table := range export.NewDictionary().Tables {
+		columns := make(map[string]string, len(table.Columns))
+
+		for _, column := range table.Columns {
+			columns[column.Name] = column.Description
+		}
+
+		s.descriptions[table.Name] = columns
+	}
+
+	return s
+}
+
+// ref returns a reference to the component schema of a struct. The component is named after the
+// type, unless a name is given.
+func (s *schemas) ref(v interface{}, name ...string) *Schema {
+	t := reflect.TypeOf(v)
+
+	if len(name) > 0 {
+		s.names[t] = name[0]
+	}
+
+	return s.of(t)
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.of(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		name, ok := s.names[t]
+		if !ok {
+			name = t.Name()
+			s.names[t] = name
+		}
+
+		if _, ok := s.components[name]; !ok {
+			// Registered before the fields are generated, so recursive types terminate.
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// Interfaces accept any value.
+	return &Schema{}
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	o := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	descriptions := s.descriptions[modelTables[t]]
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := models.ColumnName(field)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		p := s.of(field.Type)
+		p.Description = descriptions[name]
+
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			key, param, _ := strings.Cut(rule, "=")
+
+			switch key {
+			case "required":
+				o.Required = append(o.Required, name)
+			case "max":
+				if n, err := strconv.Atoi(param); err == nil {
+					p.MaxLength = &n
+				}
+			case "count":
+				p.Pattern = "^[0-9]*$"
+			case "oneof":
+				p.Enum = strings.Fields(param)
+			}
+
+			// Counts are described in the data dictionary already.
+			if d, ok := ruleDescriptions[key]; ok && (p.Description == "" || key != "count") {
+				p.Description = strings.TrimSpace(p.Description + " Accepts: " + d)
+			}
+		}
+
+		o.Properties[name] = p
+	}
+
+	return o
+}
@@ -0,0 +1,159 @@
+package goplg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Measurement of a local working tree, without SourceGraph and the database.
+
+type Library struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	CodeLines int    `json:"code_lines"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ModulePath parses the path of the "module" directive of a go.mod file.
+func ModulePath(modFile string) string {
+	scanner := bufio.NewScanner(strings.NewReader(modFile))
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"")
+		}
+	}
+
+	return ""
+}
+
+// LocalLibraries parses the libraries of every go.mod file in the tree, the same way the libraries
+// of the repositories are parsed. Vendored and test data modules are skipped.
+func LocalLibraries(root string) ([]Library, error) {
+	var libraries []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			switch d.Name() {
+			case "vendor", "testdata", ".git":
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.Name() != "go.mod" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		libraries = append(libraries, parseLibrariesFromModFile(string(content))...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Library
+
+	for _, library := range removeDuplicates(libraries) {
+		parts := strings.Fields(library)
+		if len(parts) != 2 {
+			continue
+		}
+
+		result = append(result, Library{Module: parts[0], Version: parts[1]})
+	}
+
+	return result, nil
+}
+
+// DownloadLibraries downloads the libraries to the module cache with "go mod download", and
+// calculates their lines of code. Failures are recorded to the library, instead of stopping.
+func DownloadLibraries(dir string, libraries []Library) error {
+	if len(libraries) == 0 {
+		return nil
+	}
+
+	args := []string{"mod", "download", "-json"}
+
+	for _, l := range libraries {
+		args = append(args, l.Module+"@"+l.Version)
+	}
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Exits with an error, if any of the modules fails. The errors are reported per module in the output.
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		return fmt.Errorf("go mod download: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	type download struct {
+		Path    string
+		Version string
+		Dir     string
+		Error   string
+	}
+
+	dirs := make(map[string]download)
+
+	decoder := json.NewDecoder(&stdout)
+
+	for {
+		var d download
+
+		if err := decoder.Decode(&d); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		dirs[d.Path+"@"+d.Version] = d
+	}
+
+	for i, l := range libraries {
+		d, ok := dirs[l.Module+"@"+l.Version]
+
+		switch {
+		case !ok:
+			libraries[i].Error = "not downloaded"
+		case d.Error != "":
+			libraries[i].Error = d.Error
+		default:
+			lines, err := CodeLines(d.Dir)
+			if err != nil {
+				libraries[i].Error = err.Error()
+				continue
+			}
+
+			libraries[i].CodeLines = lines
+		}
+	}
+
+	return nil
+}
@@ -2,14 +2,6 @@ package goplg
 
 // GitHub
 
-type GitHubResponse struct {
-	Data GitHubDataStruct `json:"data"`
-}
-
-type GitHubDataStruct struct {
-	Repository GitHubRepositoryStruct `json:"repository"`
-}
-
 type GitHubRepositoryStruct struct {
 	DefaultBranchRef DefaultBranchRefStruct      `json:"defaultBranchRef"`
 	OpenIssues       GitHubOpenIssuesStruct      `json:"openIssues"`
@@ -58,12 +50,24 @@ type GitHubLanguagesStruct struct {
 	TotalSize int `json:"totalSize"`
 }
 
-// SourceGraph
+// Data of the search field of searchGitHubQuery.
+type GitHubSearchStruct struct {
+	RepositoryCount int                            `json:"repositoryCount"`
+	PageInfo        GitHubPageInfoStruct           `json:"pageInfo"`
+	Nodes           []GitHubSearchRepositoryStruct `json:"nodes"`
+}
 
-type SourceGraphResponse struct {
-	Data SourceGraphDataStruct `json:"data"`
+type GitHubPageInfoStruct struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
 }
 
+type GitHubSearchRepositoryStruct struct {
+	NameWithOwner string `json:"nameWithOwner"`
+}
+
+// SourceGraph
+
 type SourceGraphDataStruct struct {
 	Search SourceGraphSearchStruct `json:"search"`
 }
@@ -73,9 +77,36 @@ type SourceGraphSearchStruct struct {
 }
 
 type SourceGraphResultsStruct struct {
+	LimitHit     bool                            `json:"limitHit"`
+	Timedout     []SourceGraphRepositoriesStruct `json:"timedout"`
 	Repositories []SourceGraphRepositoriesStruct `json:"repositories"`
 }
 
 type SourceGraphRepositoriesStruct struct {
 	Name string `json:"name"`
 }
+
+// Data of modFileQuery. Fields are left empty, when the repository or the file doesn't exist.
+type SourceGraphModFileStruct struct {
+	Repository SourceGraphRepositoryStruct `json:"repository"`
+}
+
+type SourceGraphRepositoryStruct struct {
+	DefaultBranch SourceGraphDefaultBranchStruct `json:"defaultBranch"`
+}
+
+type SourceGraphDefaultBranchStruct struct {
+	Target SourceGraphTargetStruct `json:"target"`
+}
+
+type SourceGraphTargetStruct struct {
+	Commit SourceGraphCommitStruct `json:"commit"`
+}
+
+type SourceGraphCommitStruct struct {
+	Blob SourceGraphBlobStruct `json:"blob"`
+}
+
+type SourceGraphBlobStruct struct {
+	Content string `json:"content"`
+}
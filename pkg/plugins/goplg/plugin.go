@@ -1,55 +1,79 @@
 package goplg
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/github"
+	"github.com/haapjari/glass/pkg/graphql"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/metrics/prom"
 	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/pool"
 	"github.com/haapjari/glass/pkg/utils"
-	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 )
 
-var (
-	GITHUB_API_TOKEN                string = fmt.Sprintf("%v", utils.GetGithubApiToken())
-	GITHUB_USERNAME                 string = fmt.Sprintf("%v", utils.GetGithubUsername())
-	SOURCEGRAPH_GRAPHQL_API_BASEURL string = utils.GetSourceGraphGraphQlApiBaseurl()
-	GITHUB_GRAPHQL_API_BASEURL      string = utils.GetGithubGraphQlApiBaseurl()
-	REPOSITORY_API_BASEURL          string = utils.GetRepositoryApiBaseUrl()
-)
+// Name of the plugin, which its failures are recorded with.
+const Name = "go"
+
+// Primary language of the repositories the plugin collects, as GitHub names it.
+const Language = "Go"
 
 type GoPlugin struct {
-	GitHubApiToken string
-	GitHubUsername string
+	Config         *config.Config
 	HttpClient     *http.Client
 	Parser         *Parser
 	DatabaseClient *gorm.DB
-	GitHubClient   *http.Client
-	MaxThreads     int
+	GitHub         *github.Client
+	SourceGraph    *graphql.Client
+	Failures       *failures.Recorder
+	Outputs        *logging.Outputs
+	Logger         *slog.Logger
+
+	// Names of the repositories the stages are limited to, when they are retried.
+	only []string
 }
 
-func NewGoPlugin(DatabaseClient *gorm.DB) *GoPlugin {
+// Requirements of the configuration, which the plugin needs.
+var Requirements = []config.Requirement{
+	config.RequireDatabase,
+	config.RequireGitHub,
+	config.RequireSourceGraph,
+	config.RequireGoPaths,
+}
+
+// NewGoPlugin returns the plugin. Job is the id of the job, which runs the plugin, and zero
+// outside jobs. The lines the plugin logs have the fields of the plugin and the job.
+func NewGoPlugin(DatabaseClient *gorm.DB, c *config.Config, job int) *GoPlugin {
 	g := new(GoPlugin)
 
-	g.HttpClient = &http.Client{}
-	g.HttpClient.Timeout = time.Minute * 10 // TODO: Environment Variable
+	g.Config = c
 
-	tokenSource := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: GITHUB_API_TOKEN},
-	)
+	g.Logger = slog.Default().With(logging.KeyPlugin, Name)
+	if job != 0 {
+		g.Logger = g.Logger.With(logging.KeyJob, job)
+	}
 
-	g.GitHubClient = oauth2.NewClient(context.Background(), tokenSource)
+	// Requests time out by the settings of the stages.
+	g.HttpClient = &http.Client{}
+
+	g.GitHub = github.NewClient(g.HttpClient, c.GitHub.GraphQlApiBaseUrl, c.GitHub.ApiTokens, g.Logger)
+	g.GitHub.Timeout = c.Stage(config.StageEnrich).Timeout
+	g.SourceGraph = graphql.NewClient(g.HttpClient, c.SourceGraph.GraphQlApiBaseUrl, prom.ApiSourceGraph)
 	g.DatabaseClient = DatabaseClient
-	g.MaxThreads = 20
+	g.Failures = failures.NewRecorder(DatabaseClient, Name, g.Logger)
+	g.Outputs = logging.NewOutputs(DatabaseClient, job, g.Logger)
 
 	g.Parser = NewParser()
 
@@ -57,552 +81,585 @@ func NewGoPlugin(DatabaseClient *gorm.DB) *GoPlugin {
 }
 
 // Fetch Repositories and Enrich the Repositories with Metadata.
-func (g *GoPlugin) GetRepositoryMetadata(c int) {
-	g.fetchRepositories(c)
-	g.deleteDuplicateRepositories()
-	g.enrichWithMetadata()
+func (g *GoPlugin) GetRepositoryMetadata(c int) error {
+	if err := g.Fetch(c, nil); err != nil {
+		return err
+	}
+
+	if err := g.Enrich(); err != nil {
+		return err
+	}
 
 	// TODO: Alot of requests seem to result primary language repositories, which arent Go.
 	// Those have to be pruned out.
 
+	return g.Measure()
+
+	// g.enrichWithLibraryData()
+}
+
+// Fetch discovers count new repositories from each source, writes them to the database, and
+// deletes the duplicates. Without sources, the DefaultSources are searched.
+func (g *GoPlugin) Fetch(count int, sources []models.Source) error {
+	if len(sources) == 0 {
+		sources = DefaultSources
+	}
+
+	seen, err := g.repositoryNameSet()
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		if err := g.fetchSource(source, count, seen); err != nil {
+			return err
+		}
+	}
+
+	return g.deleteDuplicateRepositories()
+}
+
+// Enrich appends the metadata of GitHub to the repositories in the database. Repositories, which
+// fail, are recorded as failures.
+func (g *GoPlugin) Enrich() error {
+	return g.enrichWithMetadata()
+}
+
+// Measure calculates the codebase sizes of the repositories and their libraries. Repositories,
+// which fail, are recorded as failures.
+func (g *GoPlugin) Measure() error {
 	// TODO: Optimizations.
 	// There can be goroutine optimizations done in this function.
-	g.calcRepoSize()
+	if err := g.calcRepoSize(); err != nil {
+		return err
+	}
 
 	// TODO: Optimizations.
-	g.calcReposLibSizes()
+	return g.calcReposLibSizes()
+}
 
-	// g.enrichWithLibraryData()
+// Retry runs the stage again for the repositories. The stages of the libraries are run together,
+// so retrying any of them downloads and counts the libraries again.
+func (g *GoPlugin) Retry(stage string, repositories []string) error {
+	if len(repositories) == 0 {
+		return errors.New("no repositories to retry")
+	}
+
+	r := *g
+	r.only = repositories
+
+	switch stage {
+	case config.StageEnrich:
+		return r.enrichWithMetadata()
+	case config.StageClone:
+		return r.calcRepoSize()
+	case config.StageDependencies, config.StageDownload, config.StageCount:
+		return r.calcReposLibSizes()
+	}
+
+	return fmt.Errorf("stage %s can't be retried", stage)
 }
 
 // Delete duplicate repositories.
-func (g *GoPlugin) deleteDuplicateRepositories() {
-	repositories := g.getAllRepositories()
+func (g *GoPlugin) deleteDuplicateRepositories() error {
+	repositories, err := g.getAllRepositories()
+	if err != nil {
+		return err
+	}
 
 	duplicateRepositories := findDuplicateRepositoryEntries(repositories.RepositoryData)
 
 	amount := len(duplicateRepositories)
 
-	for i := 0; i < amount; i++ {
-		// copy the model, which is going to be deleted
-		var r models.Repository
-
-		name := duplicateRepositories[i].RepositoryName
+	g.Logger.Info("deleting duplicate repositories", logging.KeyStage, config.StageFetch, "repositories", amount)
 
-		// Find matching repository from the database.
-		if err := g.DatabaseClient.Where("repository_name = ?", name).First(&r).Error; err != nil {
-			utils.CheckErr(err)
+	// Duplicates are deleted by their ids, so the earliest repository is kept. Names aren't
+	// unique after enrich, see discoveredName.
+	for i := 0; i < amount; i++ {
+		if err := g.DatabaseClient.Delete(&models.Repository{}, duplicateRepositories[i].Id).Error; err != nil {
+			return err
 		}
-
-		// delete from database
-		g.DatabaseClient.Delete(&r)
 	}
+
+	return nil
 }
 
 // Enriches the metadata with "Original Codebase Size" variables.
-func (g *GoPlugin) calcRepoSize() {
-	// fetch all the repositories from the database.
-	repositories := g.getAllRepositories()
-
+func (g *GoPlugin) calcRepoSize() error {
 	// Check if the "tmp" directory exists.
 	if _, err := os.Stat("tmp"); os.IsNotExist(err) {
 		// Create a temporary directory to clone the repositories into.
 		if err := os.Mkdir("tmp", 0777); err != nil {
-			utils.CheckErr(err)
+			return err
 		}
 	}
 
-	// append the https:// and .git prefix and postfix the RepositoryUrl variables.
-	for i := 0; i < len(repositories.RepositoryData); i++ {
-		repositories.RepositoryData[i].RepositoryUrl = "https://" + repositories.RepositoryData[i].RepositoryUrl + ".git"
-	}
+	budget := g.diskBudget(config.StageClone, "tmp")
+
+	// Amount of clones in progress.
+	var cloning int32
 
-	// iterate through all repositories
-	for _, repo := range repositories.RepositoryData {
+	return g.inBatches(config.StageClone, func(repositories []models.Repository) error {
 		// If the OriginalCodebaseSize variable is empty, analyze the repository.
 		// Otherwise skip the repository, in order to avoid double analysis.
-		if repo.OriginalCodebaseSize == "" {
-			// Clone the repository into a temporary directory.
-			// Attempt to clone "master" branch.
-			output, err := runCommand("git", "clone", "--depth", "1", repo.RepositoryUrl, "tmp"+"/"+repo.RepositoryName)
-			if err != "" {
-				fmt.Println(err)
+		var pending []models.Repository
+
+		for _, repo := range repositories {
+			if repo.OriginalCodebaseSize == "" {
+				pending = append(pending, repo)
 			}
-			fmt.Println(output)
+		}
 
-			// Run "gocloc" and calculate the amount of lines.
-			lines := runGocloc("tmp/" + repo.RepositoryName)
+		errs := pool.Each(context.Background(), g.Config.Stage(config.StageClone).Workers, pending, func(ctx context.Context, repo models.Repository) error {
+			// Clones in progress are deleted, when they are measured.
+			budget.wait(func() bool { return atomic.LoadInt32(&cloning) > 0 })
 
-			// Update the database.
-			g.updatePrimaryCodeLinesToDatabase(repo.RepositoryName, lines)
+			atomic.AddInt32(&cloning, 1)
+			defer atomic.AddInt32(&cloning, -1)
 
-			// Delete the repository.
-			output, err = runCommand("rm", "-rf", "tmp"+"/"+repo.RepositoryName)
-			fmt.Println(output)
-			if err != "" {
-				fmt.Println(err)
-			}
+			return failures.New(config.StageClone, repo.RepositoryName, g.measureRepository(ctx, repo))
+		})
+
+		return g.Failures.Collect(config.StageClone, repositoryNames(pending), errs)
+	})
+}
+
+// Clones the repository, calculates the lines of code, and deletes the clone.
+func (g *GoPlugin) measureRepository(ctx context.Context, repo models.Repository) error {
+	// append the https:// and .git prefix and postfix the RepositoryUrl variable.
+	url := "https://" + repo.RepositoryUrl + ".git"
+	dir := "tmp" + "/" + repo.RepositoryName
+
+	// Delete the repository.
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			g.Logger.Warn("removing the clone", logging.KeyStage, config.StageClone, logging.KeyRepository, repo.RepositoryName, logging.KeyError, err)
 		}
+	}()
+
+	// Clone the repository into a temporary directory.
+	// Attempt to clone "master" branch.
+	cloneCtx, cancel := g.timeout(ctx, config.StageClone)
+	start := time.Now()
+	_, err := g.runCommand(cloneCtx, config.StageClone, repo.RepositoryName, "git", "clone", "--depth", "1", url, dir)
+	cancel()
+	if err != nil {
+		return err
 	}
+
+	prom.CloneDuration.Observe(time.Since(start).Seconds())
+	prom.CloneBytes.Observe(float64(dirSize(dir)))
+
+	// Run "gocloc" and calculate the amount of lines.
+	lines, err := CodeLines(dir)
+	if err != nil {
+		return err
+	}
+
+	// Update the database.
+	if err := g.updatePrimaryCodeLinesToDatabase(repo.RepositoryName, lines); err != nil {
+		return err
+	}
+
+	prom.RepositoriesMeasured.WithLabelValues(Name, config.StageClone).Inc()
+
+	return nil
 }
 
-func (g *GoPlugin) updatePrimaryCodeLinesToDatabase(name string, lines int) {
+func (g *GoPlugin) updatePrimaryCodeLinesToDatabase(name string, lines int) error {
 	// Copy the repository struct to a new variable.
 	var repositoryStruct models.Repository
 
 	// Find matching repository from the database.
 	if err := g.DatabaseClient.Where("repository_name = ?", name).First(&repositoryStruct).Error; err != nil {
-		utils.CheckErr(err)
+		return err
 	}
 
 	// Update the OriginalCodebaseSize variable, with calculated value.
 	repositoryStruct.OriginalCodebaseSize = strconv.Itoa(lines)
 
 	// Update the database.
-	g.DatabaseClient.Model(&repositoryStruct).Updates(repositoryStruct)
+	return g.DatabaseClient.Model(&repositoryStruct).Updates(repositoryStruct).Error
 }
 
-func (g *GoPlugin) updateLibraryCodeLinesToDatabase(name string, lines int) {
+func (g *GoPlugin) updateLibraryCodeLinesToDatabase(name string, lines int) error {
 	// Copy the repository struct to a new variable.
 	var repositoryStruct models.Repository
 
 	// Find matching repository from the database.
 	if err := g.DatabaseClient.Where("repository_name = ?", name).First(&repositoryStruct).Error; err != nil {
-		utils.CheckErr(err)
+		return err
 	}
 
 	// Update the OriginalCodebaseSize variable, with calculated value.
 	repositoryStruct.LibraryCodebaseSize = strconv.Itoa(lines)
 
 	// Update the database.
-	g.DatabaseClient.Model(&repositoryStruct).Updates(repositoryStruct)
+	return g.DatabaseClient.Model(&repositoryStruct).Updates(repositoryStruct).Error
 }
 
-// Fetches initial metadata of the repositories. Crafts a SourceGraph GraphQL request, and
-// parses the repository location to the database table.
-func (g *GoPlugin) fetchRepositories(count int) {
-	queryStr := `{
-		search(query: "lang:go + AND select:repo AND repohasfile:go.mod AND count:` + strconv.Itoa(count) + `", version:V2) { results {
-				repositories {
-					name
-				}
-			}
-		}
-	}`
-
-	rawReqBody := map[string]string{
-		"query": queryStr,
-	}
-
-	// Parse Body to JSON
-	jsonReqBody, err := json.Marshal(rawReqBody)
-	utils.CheckErr(err)
-
-	bytesReqBody := bytes.NewBuffer(jsonReqBody)
-
-	// Craft a request
-	request, err := http.NewRequest("POST", SOURCEGRAPH_GRAPHQL_API_BASEURL, bytesReqBody)
-	request.Header.Set("Content-Type", "application/json")
-	utils.CheckErr(err)
-
-	// Execute request
-	res, err := g.HttpClient.Do(request)
-	utils.CheckErr(err)
-
-	defer res.Body.Close()
-
-	// Read all bytes from the response
-	sourceGraphResponseBody, err := ioutil.ReadAll(res.Body)
-	utils.CheckErr(err)
+// Reads the repositories -tables values to memory, crafts GitHub GraphQL requests of the
+// repositories, and appends the database entries with Open Issue Count, Closed Issue Count,
+// Commit Count, Original Codebase Size, Repository Type, Primary Language, Stargazers Count,
+// Creation Date, License. Many repositories are queried in a single request, see metadataRequest.
+func (g *GoPlugin) enrichWithMetadata() error {
+	return g.inBatches(config.StageEnrich, func(repositories []models.Repository) error {
+		queries := batches(repositories, g.repositoriesPerQuery())
 
-	// Parse bytes JSON.
-	var jsonSourceGraphResponse SourceGraphResponse
-	json.Unmarshal([]byte(sourceGraphResponseBody), &jsonSourceGraphResponse)
+		results := pool.Map(context.Background(), g.Config.Stage(config.StageEnrich).Workers, queries, g.enrichRepositories)
 
-	// Write the response to Database.
-	g.writeSourceGraphResponseToDatabase(len(jsonSourceGraphResponse.Data.Search.Results.Repositories), jsonSourceGraphResponse.Data.Search.Results.Repositories)
-}
+		// A request, which fails, fails every repository of it.
+		errs := make([]error, 0, len(repositories))
 
-// Reads the repositories -tables values to memory, crafts a GitHub GraphQL requests of the
-// repositories, and appends the database entries with Open Issue Count, Closed Issue Count,
-// Commit Count, Original Codebase Size, Repository Type, Primary Language, Stargazers Count,
-// Creation Date, License.
-func (g *GoPlugin) enrichWithMetadata() {
-	r := g.getAllRepositories()
-	c := len(r.RepositoryData)
-
-	var wg sync.WaitGroup
-
-	// Semaphore is a safeguard to goroutines, to allow only "MaxThreads" run at the same time.
-	semaphore := make(chan int, g.MaxThreads)
-
-	for i := 0; i < c; i++ {
-		semaphore <- 1
-		wg.Add(1)
-
-		go func(i int) {
-			// Parse Owner and Name values from the Repository, which are used in the GraphQL query.
-			owner, name := g.Parser.ParseRepository(r.RepositoryData[i].RepositoryUrl)
-
-			// Query String
-			queryStr := fmt.Sprintf(`{
-					repository(owner: "%s", name: "%s") {
-						defaultBranchRef {
-							target {
-								... on Commit {
-								history {
-									totalCount
-								}
-							}
-						}
-					}	
-					openIssues: issues(states:OPEN) {
-						totalCount
-					}
-					closedIssues: issues(states:CLOSED) {
-						totalCount
-					}
-					languages {
-						totalSize
-					}
-					stargazerCount
-					licenseInfo {
-						key
-					}
-					createdAt
-					latestRelease{
-						publishedAt
-					}
-					primaryLanguage{
-						name
-					}
+		for i, result := range results {
+			for j, repository := range queries[i] {
+				err := result.Err
+				if err == nil {
+					err = result.Value[j]
 				}
-			}`, owner, name)
-
-			rawGithubRequestBody := map[string]string{
-				"query": queryStr,
-			}
 
-			// Parse body to JSON.
-			jsonGithubRequestBody, err := json.Marshal(rawGithubRequestBody)
-			utils.CheckErr(err)
+				if err != nil {
+					errs = append(errs, failures.New(config.StageEnrich, repository.RepositoryName, err))
+					continue
+				}
 
-			bytesReqBody := bytes.NewBuffer(jsonGithubRequestBody)
+				prom.RepositoriesEnriched.WithLabelValues(Name).Inc()
 
-			// Craft a request.
-			githubRequest, err := http.NewRequest("POST", GITHUB_GRAPHQL_API_BASEURL, bytesReqBody)
-			if err != nil {
-				log.Fatalln(err)
+				errs = append(errs, nil)
 			}
+		}
 
-			githubRequest.Header.Set("Accept", "application/vnd.github.v3+json")
+		return g.Failures.Collect(config.StageEnrich, repositoryNames(repositories), errs)
+	})
+}
 
-			// Execute a request with Oauth2 client.
-			githubResponse, err := g.GitHubClient.Do(githubRequest)
-			utils.CheckErr(err)
+// Queries the metadata of the repositories in a single request, and writes it to the database.
+// Returns the errors of the repositories in their order, or an error of the whole request.
+func (g *GoPlugin) enrichRepositories(ctx context.Context, repositories []models.Repository) ([]error, error) {
+	names := make([]string, len(repositories))
 
-			defer githubResponse.Body.Close()
+	for i, repository := range repositories {
+		// Parse Owner and Name values from the Repository, which are used in the GraphQL query.
+		_, names[i] = g.Parser.ParseRepository(repository.RepositoryUrl)
+	}
 
-			// Read the response bytes to a variable.
-			githubResponseBody, err := ioutil.ReadAll(githubResponse.Body)
-			utils.CheckErr(err)
+	// Waits for the rate limit, and retries the transient errors. The timeout of the stage is
+	// the timeout of each request.
+	response, err := g.GitHub.Do(ctx, metadataRequest(g.Parser, repositories))
+	if err != nil {
+		return nil, err
+	}
 
-			// Parse bytes to JSON.
-			var jsonGithubResponse GitHubResponse
-			json.Unmarshal([]byte(githubResponseBody), &jsonGithubResponse)
+	errs := make([]error, len(repositories))
 
-			var existingRepositoryStruct models.Repository
+	for i, repository := range repositories {
+		var metadata GitHubRepositoryStruct
 
-			// Search for existing model, which matches the id and copy the values to the "existingRepositoryStruct" variable.
-			if err := g.DatabaseClient.Where("id = ?", r.RepositoryData[i].Id).First(&existingRepositoryStruct).Error; err != nil {
-				utils.CheckErr(err)
-			}
-
-			// Create new struct, with updated values.
-			var newRepositoryStruct models.Repository
+		errs[i] = response.Field(repositoryAlias(i), &metadata)
+		if errs[i] == nil {
+			errs[i] = g.updateRepositoryMetadata(repository, names[i], metadata)
+		}
+	}
 
-			newRepositoryStruct.RepositoryName = name
-			newRepositoryStruct.RepositoryUrl = r.RepositoryData[i].RepositoryUrl
-			newRepositoryStruct.OpenIssueCount = strconv.Itoa(jsonGithubResponse.Data.Repository.OpenIssues.TotalCount)
-			newRepositoryStruct.ClosedIssueCount = strconv.Itoa(jsonGithubResponse.Data.Repository.ClosedIssues.TotalCount)
-			newRepositoryStruct.CommitCount = strconv.Itoa(jsonGithubResponse.Data.Repository.DefaultBranchRef.Target.History.TotalCount)
-			newRepositoryStruct.RepositoryType = "primary"
-			newRepositoryStruct.PrimaryLanguage = jsonGithubResponse.Data.Repository.PrimaryLanguage.Name
-			newRepositoryStruct.CreationDate = jsonGithubResponse.Data.Repository.CreatedAt
-			newRepositoryStruct.StargazerCount = strconv.Itoa(jsonGithubResponse.Data.Repository.StargazerCount)
-			newRepositoryStruct.LicenseInfo = jsonGithubResponse.Data.Repository.LicenseInfo.Key
-			newRepositoryStruct.LatestRelease = jsonGithubResponse.Data.Repository.LatestRelease.PublishedAt
+	return errs, nil
+}
 
-			// Update the existing model, with values from the new struct.
-			g.DatabaseClient.Model(&existingRepositoryStruct).Updates(newRepositoryStruct)
+// Appends the metadata of GitHub to the repository in the database.
+func (g *GoPlugin) updateRepositoryMetadata(repository models.Repository, name string, metadata GitHubRepositoryStruct) error {
+	var existingRepositoryStruct models.Repository
 
-			defer func() { <-semaphore }()
-		}(i)
-		wg.Done()
+	// Search for existing model, which matches the id and copy the values to the "existingRepositoryStruct" variable.
+	if err := g.DatabaseClient.Where("id = ?", repository.Id).First(&existingRepositoryStruct).Error; err != nil {
+		return err
 	}
 
-	wg.Wait()
-
-	// When the Channel Length is not 0, there is still running Threads.
-	for !(len(semaphore) == 0) {
-		continue
-	}
+	// Create new struct, with updated values.
+	var newRepositoryStruct models.Repository
+
+	newRepositoryStruct.RepositoryName = name
+	newRepositoryStruct.RepositoryUrl = repository.RepositoryUrl
+	newRepositoryStruct.OpenIssueCount = strconv.Itoa(metadata.OpenIssues.TotalCount)
+	newRepositoryStruct.ClosedIssueCount = strconv.Itoa(metadata.ClosedIssues.TotalCount)
+	newRepositoryStruct.CommitCount = strconv.Itoa(metadata.DefaultBranchRef.Target.History.TotalCount)
+	newRepositoryStruct.RepositoryType = "primary"
+	newRepositoryStruct.PrimaryLanguage = metadata.PrimaryLanguage.Name
+	newRepositoryStruct.CreationDate = metadata.CreatedAt
+	newRepositoryStruct.StargazerCount = strconv.Itoa(metadata.StargazerCount)
+	newRepositoryStruct.LicenseInfo = metadata.LicenseInfo.Key
+	newRepositoryStruct.LatestRelease = metadata.LatestRelease.PublishedAt
+
+	// Update the existing model, with values from the new struct.
+	return g.DatabaseClient.Model(&existingRepositoryStruct).Updates(newRepositoryStruct).Error
 }
 
 // Function gets a list of repositories and returns a map of repository names and their dependencies (parsed from go.mod file).
-func (g *GoPlugin) createRepositoryDependenciesMap(repos []models.Repository) map[string][]string {
-	repoCount := len(repos)
+// Repositories, which dependencies couldn't be read, are recorded as failures, and left out of the map.
+func (g *GoPlugin) createRepositoryDependenciesMap(repos []models.Repository) (map[string][]string, error) {
+	results := pool.Map(context.Background(), g.Config.Stage(config.StageDependencies).Workers, repos, g.repositoryDependencies)
 
 	// Map of Repository Name (as key) and go.mod -file's dependencies.
 	libs := make(map[string][]string)
 
-	// ---
-	var wg sync.WaitGroup
-
-	semaphore := make(chan struct{}, 20)
-
-	for i := 0; i < repoCount; i++ {
-		// Acquire a token from the semaphore
-		wg.Add(1)
-		semaphore <- struct{}{}
-
-		// Launch a goroutine
-		go func(i int) {
-			repoUrl := repos[i].RepositoryUrl
-			repoName := repos[i].RepositoryName
-
-			// Query String
-			queryString := fmt.Sprintf(`{
-			repository(name: "%s") {
-				defaultBranch {
-					target {
-						commit {
-							blob(path: "go.mod") {
-								content
-							}
-						}
-					}
-				}
-			}
-		}`, repoUrl)
+	errs := make([]error, len(results))
 
-			// Construct the Query
-			rawRequestBody := map[string]string{
-				"query": queryString,
-			}
+	for i, r := range results {
+		if r.Err != nil {
+			errs[i] = failures.New(config.StageDependencies, repos[i].RepositoryName, r.Err)
+			continue
+		}
 
-			// Parse Body from Map to JSON
-			jsonRequestBody, err := json.Marshal(rawRequestBody)
-			utils.CheckErr(err)
+		libs[repos[i].RepositoryName] = append(libs[repos[i].RepositoryName], r.Value...)
+	}
 
-			// Convert the Body from JSON to Bytes
-			requestBodyInBytes := bytes.NewBuffer(jsonRequestBody)
+	return libs, g.Failures.Collect(config.StageDependencies, repositoryNames(repos), errs)
+}
 
-			// Craft a Request
-			request, err := http.NewRequest("POST", SOURCEGRAPH_GRAPHQL_API_BASEURL, requestBodyInBytes)
-			request.Header.Set("Content-Type", "application/json")
-			utils.CheckErr(err)
+// Reads the libraries of the go.mod file of the repository, and the go.mod files it replaces.
+func (g *GoPlugin) repositoryDependencies(ctx context.Context, repository models.Repository) ([]string, error) {
+	repoUrl := repository.RepositoryUrl
 
-			// Execute Request
-			res, err := g.HttpClient.Do(request)
-			utils.CheckErr(err)
+	ctx, cancel := g.timeout(ctx, config.StageDependencies)
+	defer cancel()
 
-			// Close the Body, after surrounding function returns.
-			defer res.Body.Close()
+	response, err := g.SourceGraph.Do(ctx, modFileRequest(repoUrl))
+	if err != nil {
+		return nil, err
+	}
 
-			// Read all bytes from the response. (Empties the res.Body)
-			sourceGraphResponseBody, err := ioutil.ReadAll(res.Body)
-			utils.CheckErr(err)
+	var data SourceGraphModFileStruct
+	if err := response.Decode(&data); err != nil {
+		return nil, err
+	}
 
-			// Parse JSON with "https://github.com/buger/jsonparser"
-			outerModFile := extractDefaultBranchCommitBlobContent(sourceGraphResponseBody)
+	outerModFile := data.Repository.DefaultBranch.Target.Commit.Blob.Content
 
-			// Parse the libraries from the go.mod file and inner go.mod files of a project and save them to variables.
-			var (
-				libraries     []string
-				innerModFiles []string
-			)
+	// Parse the name of libraries from modfile to a slice.
+	libraries := parseLibrariesFromModFile(outerModFile)
 
-			// If the go.mod file has "replace" - keyword, it has inner go.mod files, parse them to a list.
-			if checkInnerModFiles(outerModFile) {
-				// Parse the ending from URL.
-				owner, repo, err := parseRepositoryName(repoUrl)
-				utils.CheckErr(err)
+	// If the go.mod file has "replace" - keyword, it has inner go.mod files,
+	// append libraries from inner go.mod files to the libraries slice.
+	if checkInnerModFiles(outerModFile) {
+		// Parse the ending from URL.
+		owner, repo, err := parseRepositoryName(repoUrl)
+		if err != nil {
+			return nil, err
+		}
 
-				innerModFiles = parseInnerModFiles(outerModFile, owner+"/"+repo)
+		for _, innerModFile := range parseInnerModFiles(outerModFile, owner+"/"+repo) {
+			// Perform a GET request, to get the content of the inner modfile.
+			// Append the libraries from the inner modfile to the libraries slice.
+			content, err := g.performGetRequest(ctx, innerModFile)
+			if err != nil {
+				return nil, err
 			}
 
-			// Parse the name of libraries from modfile to a slice.
-			libraries = parseLibrariesFromModFile(outerModFile)
-
-			// If the go.mod file has "replace" - keyword, it has inner go.mod files,
-			// append libraries from inner go.mod files to the libraries slice.
-			if checkInnerModFiles(outerModFile) {
-				// Parse the library names of the inner go.mod files, and append them to the libraries slice.
-				for i := 0; i < len(innerModFiles); i++ {
-					// Perform a GET request, to get the content of the inner modfile.
-					// Append the libraries from the inner modfile to the libraries slice.
-					libraries = append(libraries, parseLibrariesFromModFile(performGetRequest(innerModFiles[i]))...)
-				}
-			}
+			libraries = append(libraries, parseLibrariesFromModFile(content)...)
+		}
+	}
 
-			// Remove duplicates from the libraries slice.
-			libraries = removeDuplicates(libraries)
+	// Remove duplicates from the libraries slice.
+	return removeDuplicates(libraries), nil
+}
 
-			// Append all the values to the map.
-			libs[repoName] = append(libs[repoName], libraries...)
+// Function takes repos and libs and calculates the amount of library code lines for each repository, and writes that to db.
+// Requires the libraries to be downloaded in the file system. Only the repositories, which
+// libraries were all downloaded, are counted. Repositories, which libraries couldn't be counted,
+// are recorded as failures.
+func (g *GoPlugin) calculateLibraryCodeLines(repos []models.Repository, libs map[string][]string, downloaded map[string]bool) error {
+	var counted []models.Repository
+
+	for _, repo := range repos {
+		if downloaded[repo.RepositoryName] {
+			counted = append(counted, repo)
+		}
+	}
 
-			// Release the token
-			<-semaphore
+	// Libraries shared by the repositories are measured once.
+	var paths []string
 
-			// Tell the WaitGroup that we're done
-			wg.Done()
-		}(i)
+	for _, repo := range counted {
+		for _, lib := range libs[repo.RepositoryName] {
+			paths = append(paths, g.Config.TempGoPath+"/"+"pkg/mod"+"/"+parseGoLibraryUrl(lib))
+		}
 	}
 
-	// Wait for all the goroutines to finish
-	wg.Wait()
+	paths = removeDuplicates(paths)
 
-	return libs
-}
+	results := pool.Map(context.Background(), g.Config.Stage(config.StageCount).Workers, paths, func(ctx context.Context, path string) (int, error) {
+		return CodeLines(path)
+	})
 
-// Function takes repos and libs and calculates the amount of library code lines for each repository, and writes that to db.
-// Requires the libraries to be downloaded in the file system.
-func (g *GoPlugin) calculateLibraryCodeLines(repos []models.Repository, libs map[string][]string) {
-	repoCount := len(repos)
-	var wg sync.WaitGroup
+	// Map of library path (as key) and the amount of code lines.
+	lines := make(map[string]pool.Result[int], len(paths))
 
-	// ---
-	cache := make(map[string]int)
-	var cacheLock sync.Mutex
+	for i, r := range results {
+		lines[paths[i]] = r
+	}
+
+	errs := make([]error, len(counted))
 
-	// TODO
 	// Loop through repositories and libraries, and calculate the amount library code lines.
-	for i := 0; i < repoCount; i++ {
-		repoName := repos[i].RepositoryName
-		libCount := len(libs[repoName])
-		semaphore := make(chan struct{}, 20)
-		totalLibraryCodeLines := 0
-
-		// Lock Cache for Race Conditions.
-		cacheLock.Lock()
-
-		if lines, ok := cache[repoName]; ok {
-			totalLibraryCodeLines = lines // if the results are in the cache, use them
-		} else {
-			// if the results are not in the cache, calculate them and add them to the cache
-			for j := 0; j < libCount; j++ {
-				wg.Add(1)
-
-				go func(i int) {
-					semaphore <- struct{}{} // reserve
-					libPath := utils.GetTempGoPath() + "/" + "pkg/mod" + "/" + parseGoLibraryUrl(libs[repoName][j])
-					lines := runGocloc(libPath)
-					totalLibraryCodeLines += lines
-					<-semaphore // release
-
-					wg.Done()
-				}(i)
-
-				wg.Wait()
+	for i, repo := range counted {
+		errs[i] = failures.New(config.StageCount, repo.RepositoryName, func() error {
+			totalLibraryCodeLines := 0
+
+			for _, lib := range libs[repo.RepositoryName] {
+				r := lines[g.Config.TempGoPath+"/"+"pkg/mod"+"/"+parseGoLibraryUrl(lib)]
+				if r.Err != nil {
+					return r.Err
+				}
+
+				totalLibraryCodeLines += r.Value
 			}
-			// add the results to the cache
-			cache[repoName] = totalLibraryCodeLines
-		}
-		cacheLock.Unlock()
 
-		g.updateLibraryCodeLinesToDatabase(repoName, totalLibraryCodeLines)
+			if err := g.updateLibraryCodeLinesToDatabase(repo.RepositoryName, totalLibraryCodeLines); err != nil {
+				return err
+			}
+
+			prom.RepositoriesMeasured.WithLabelValues(Name, config.StageCount).Inc()
+
+			return nil
+		}())
 	}
+
+	return g.Failures.Collect(config.StageCount, repositoryNames(counted), errs)
 }
 
-// Loop through the repositories, and download the libraries to the local machine.
+// Loop through the repositories, and download the libraries to the local machine. Returns the
+// repositories, which libraries were all downloaded. Repositories, which libraries failed to
+// download, or were not downloaded, because the disk budget was exceeded, are recorded as failures.
 // TODO: All the repositories are downloaded modified to the same go.mod file - need to address this.
-func (g *GoPlugin) downloadGoLibraries(repos []models.Repository, libs map[string][]string) {
-	repoCount := len(repos)
+func (g *GoPlugin) downloadGoLibraries(repos []models.Repository, libs map[string][]string) (downloaded map[string]bool, err error) {
+	// Only the repositories, which dependencies were read, are downloaded.
+	var pending []models.Repository
 
-	// Reinitialize - allow 20 concurrent goroutines.
-	semaphore := make(chan struct{}, 20)
-	var wg sync.WaitGroup
+	for _, repo := range repos {
+		if _, ok := libs[repo.RepositoryName]; ok {
+			pending = append(pending, repo)
+		}
+	}
 
+	// go.mod and go.sum are shared, so a single library is downloaded at a time.
 	var goModLock sync.Mutex
 
-	// Read GOPATH variables from the environment.
-	tempGoPath := utils.GetTempGoPath()
-	goPath := utils.GetGoPath()
+	budget := g.diskBudget(config.StageDownload, g.Config.TempGoPath)
 
-	// Change GOPATH to point to temporary directory.
-	os.Setenv("GOPATH", tempGoPath)
+	// Read GOPATH variables from the configuration.
+	tempGoPath := g.Config.TempGoPath
+	goPath := g.Config.GoPath
 
 	// Copy and backup go.mod and go.sum files.
 	// This is due to the fact that the go.mod file is modified when downloading libraries,
 	// and we don't want to modify the original go.mod file.
-	utils.CopyFile("go.mod", "go.mod.bak")
-	utils.CopyFile("go.sum", "go.sum.bak")
+	if err := backupGoModFiles(); err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < repoCount; i++ {
-		wg.Add(1)
-		semaphore <- struct{}{}
+	// Change GOPATH to point to temporary directory.
+	os.Setenv("GOPATH", tempGoPath)
 
-		go func(i int) {
-			repoName := repos[i].RepositoryName
-			libCount := len(libs[repoName])
+	defer func() {
+		// Change GOPATH to point back to the original directory.
+		os.Setenv("GOPATH", goPath)
 
-			// TODO: There might be ways to optimize this.
-			for i := 0; i < libCount; i++ {
-				libUrl := parseUrlToDownloadFormat(libs[repoName][i])
+		// Reset go.mod and go.sum files.
+		err = errors.Join(err, restoreGoModFiles(), utils.RemoveFile("go.mod.bak"), utils.RemoveFile("go.sum.bak"))
+	}()
 
-				goModLock.Lock()
+	errs := pool.Each(context.Background(), g.Config.Stage(config.StageDownload).Workers, pending, func(ctx context.Context, repo models.Repository) error {
+		var failed []error
 
-				output, err := runCommand("go", "get", "-d", "-v", libUrl)
-				if err != "" {
-					fmt.Println(err)
-				}
+		// TODO: There might be ways to optimize this.
+		for _, lib := range libs[repo.RepositoryName] {
+			libUrl := parseUrlToDownloadFormat(lib)
 
-				// Reset go.mod and go.sum files.
-				utils.RemoveFile("go.mod")
-				utils.RemoveFile("go.sum")
-				utils.CopyFile("go.mod.bak", "go.mod")
-				utils.CopyFile("go.sum.bak", "go.sum")
+			goModLock.Lock()
 
+			if budget.exceeded() {
 				goModLock.Unlock()
+				failed = append(failed, fmt.Errorf("%s was not downloaded: %w", libUrl, failures.ErrDiskBudget))
+				break
+			}
 
-				if output != "" {
-					fmt.Println(output)
-				}
+			downloadCtx, cancel := g.timeout(ctx, config.StageDownload)
+			_, err := g.runCommand(downloadCtx, config.StageDownload, repo.RepositoryName, "go", "get", "-d", "-v", libUrl)
+			cancel()
+			if err != nil {
+				failed = append(failed, err)
+				prom.ModuleDownloads.WithLabelValues("failed").Inc()
+			} else {
+				prom.ModuleDownloads.WithLabelValues("succeeded").Inc()
 			}
 
-			wg.Done()
-		}(i)
+			// Reset go.mod and go.sum files. Broken files would fail every download after
+			// this one, so the stage is stopped.
+			if err := restoreGoModFiles(); err != nil {
+				goModLock.Unlock()
+				return err
+			}
+
+			goModLock.Unlock()
+		}
+
+		return failures.New(config.StageDownload, repo.RepositoryName, errors.Join(failed...))
+	})
+
+	downloaded = make(map[string]bool)
+
+	for i, err := range errs {
+		if err == nil {
+			downloaded[pending[i].RepositoryName] = true
+		}
 	}
 
-	wg.Wait()
+	return downloaded, g.Failures.Collect(config.StageDownload, repositoryNames(pending), errs)
+}
 
-	// Change GOPATH to point back to the original directory.
-	os.Setenv("GOPATH", goPath)
+// Copies go.mod and go.sum files to their backups.
+func backupGoModFiles() error {
+	return errors.Join(utils.CopyFile("go.mod", "go.mod.bak"), utils.CopyFile("go.sum", "go.sum.bak"))
+}
 
-	// Reset go.mod and go.sum files.
-	utils.RemoveFile("go.mod")
-	utils.RemoveFile("go.sum")
-	utils.CopyFile("go.mod.bak", "go.mod")
-	utils.CopyFile("go.sum.bak", "go.sum")
-	utils.RemoveFile("go.mod.bak")
-	utils.RemoveFile("go.sum.bak")
+// Resets go.mod and go.sum files from their backups.
+func restoreGoModFiles() error {
+	return errors.Join(
+		utils.RemoveFile("go.mod"),
+		utils.RemoveFile("go.sum"),
+		utils.CopyFile("go.mod.bak", "go.mod"),
+		utils.CopyFile("go.sum.bak", "go.sum"),
+	)
+}
 
-	// Prune the tmp/ folder, if we arent in development mode.
-	if !(utils.GetLocalenv() == "development") {
-		os.RemoveAll(utils.GetTempGoPath())
+// Prune the downloaded libraries, if we arent in development mode.
+func (g *GoPlugin) pruneGoLibraries() error {
+	if !(g.Config.LocalEnv == "development") {
+		return os.RemoveAll(g.Config.TempGoPath)
 	}
+
+	return nil
 }
 
 // TODO
 // Enrich the values in the repositories -table with the codebase sizes of the libraries, and append them to the database.
 // Before running the gocloc, the vendor means, that the local path is different.
-func (g *GoPlugin) calcReposLibSizes() {
-	repos := g.getAllRepositories()
+// Repositories are measured in batches of the download stage, and the libraries are pruned after
+// every batch, so the batch size and the disk budget limit the disk space used. A repository,
+// which fails in a stage, is left out of the stages after it.
+func (g *GoPlugin) calcReposLibSizes() error {
+	return g.inBatches(config.StageDownload, func(repos []models.Repository) error {
+		// Map of Repository Name (as key) and go.mod -file's dependencies.
+		libs, err := g.createRepositoryDependenciesMap(repos)
+		if err != nil {
+			return err
+		}
 
-	// Map of Repository Name (as key) and go.mod -file's dependencies.
-	libs := g.createRepositoryDependenciesMap(repos.RepositoryData)
+		downloaded, err := g.downloadGoLibraries(repos, libs)
+		if err != nil {
+			return errors.Join(err, g.pruneGoLibraries())
+		}
+
+		err = g.calculateLibraryCodeLines(repos, libs, downloaded)
 
-	g.downloadGoLibraries(repos.RepositoryData, libs)
-	g.calculateLibraryCodeLines(repos.RepositoryData, libs)
+		return errors.Join(err, g.pruneGoLibraries())
+	})
 }
@@ -0,0 +1,187 @@
+package goplg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/graphql"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+// Query documents of the plugin. Values are passed as variables of the documents.
+//
+// Metadata of many repositories is queried from GitHub in a single request, each repository as
+// an aliased field of the query, r0, r1 and so on. A request costs rate limit points by the
+// connections it reads, so the repositories of a request are limited by GITHUB_QUERY_BATCH_SIZE
+// and GITHUB_QUERY_MAX_COST.
+
+// GitHub
+
+// Fields of a repository, which enrich reads.
+const metadataFragment = `fragment metadata on Repository {
+	defaultBranchRef {
+		target {
+			... on Commit {
+				history {
+					totalCount
+				}
+			}
+		}
+	}
+	openIssues: issues(states:OPEN) {
+		totalCount
+	}
+	closedIssues: issues(states:CLOSED) {
+		totalCount
+	}
+	languages {
+		totalSize
+	}
+	stargazerCount
+	licenseInfo {
+		key
+	}
+	createdAt
+	latestRelease{
+		publishedAt
+	}
+	primaryLanguage{
+		name
+	}
+}`
+
+// Connections of metadataFragment: history, open issues, closed issues and languages. GitHub
+// charges a point for every 100 connections of a request, and at least a point.
+const metadataConnections = 4
+
+// Returns the amount of repositories in a request, which stays within the batch size and the cost
+// ceiling of the configuration.
+func (g *GoPlugin) repositoriesPerQuery() int {
+	n := g.Config.GitHub.QueryMaxCost * 100 / metadataConnections
+
+	if size := g.Config.GitHub.QueryBatchSize; size < n {
+		n = size
+	}
+
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+func repositoryAlias(i int) string {
+	return fmt.Sprintf("r%d", i)
+}
+
+// Returns the request of the metadata of the repositories, with the rate limit of the request.
+// Owners and names of the repositories are the variables owner0, name0, owner1 and so on.
+func metadataRequest(p *Parser, repositories []models.Repository) graphql.Request {
+	var (
+		parameters []string
+		fields     strings.Builder
+	)
+
+	variables := make(map[string]interface{}, 2*len(repositories))
+
+	for i, repository := range repositories {
+		owner, name := p.ParseRepository(repository.RepositoryUrl)
+
+		variables[fmt.Sprintf("owner%d", i)] = owner
+		variables[fmt.Sprintf("name%d", i)] = name
+
+		parameters = append(parameters, fmt.Sprintf("$owner%d: String!, $name%d: String!", i, i))
+
+		fmt.Fprintf(&fields, "\t%s: repository(owner: $owner%d, name: $name%d) {\n\t\t...metadata\n\t}\n", repositoryAlias(i), i, i)
+	}
+
+	query := fmt.Sprintf("query RepositoryMetadata(%s) {\n%s\trateLimit {\n\t\tcost\n\t\tremaining\n\t\tresetAt\n\t}\n}\n%s", strings.Join(parameters, ", "), fields.String(), metadataFragment)
+
+	return graphql.Request{Query: query, OperationName: "RepositoryMetadata", Variables: variables}
+}
+
+// Searches the repositories of the search query of GitHub a page at a time, e.g.
+// "language:go stars:>100". After is the cursor of the previous page.
+const searchGitHubQuery = `query SearchGitHub($query: String!, $after: String) {
+	search(query: $query, type: REPOSITORY, first: 100, after: $after) {
+		repositoryCount
+		pageInfo {
+			hasNextPage
+			endCursor
+		}
+		nodes {
+			... on Repository {
+				nameWithOwner
+			}
+		}
+	}
+	rateLimit {
+		cost
+		remaining
+		resetAt
+	}
+}`
+
+func searchGitHubRequest(query string, after string) graphql.Request {
+	variables := map[string]interface{}{"query": query}
+	if after != "" {
+		variables["after"] = after
+	}
+
+	return graphql.Request{Query: searchGitHubQuery, OperationName: "SearchGitHub", Variables: variables}
+}
+
+// SourceGraph
+
+// Searches the repositories of the search query, e.g. "lang:go select:repo count:100". LimitHit
+// tells, that the search found more repositories than the count, or timed out, and timedout lists
+// the repositories, which weren't searched in time.
+const searchRepositoriesQuery = `query SearchRepositories($query: String!) {
+	search(query: $query, version: V2) {
+		results {
+			limitHit
+			timedout {
+				name
+			}
+			repositories {
+				name
+			}
+		}
+	}
+}`
+
+// Repositories, which fetch searches without sources.
+const repositoriesSearch = `lang:go select:repo repohasfile:go.mod repo:^github\.com/`
+
+// Returns the search of the repositories of the window of the query, up to the count. Queries,
+// which don't select the repositories, select them, since only the repositories are read. The
+// window matches the names case-insensitively.
+func searchRepositoriesRequest(query string, w window, count int) graphql.Request {
+	if !strings.Contains(query, "select:repo") {
+		query += " select:repo"
+	}
+
+	query = fmt.Sprintf("%s repo:(?i)^%s count:%d", query, regexp.QuoteMeta(w.prefix), count)
+
+	return graphql.Request{Query: searchRepositoriesQuery, OperationName: "SearchRepositories", Variables: map[string]interface{}{"query": query}}
+}
+
+// Reads the go.mod file of the default branch of the repository, e.g. "github.com/owner/name".
+const modFileQuery = `query ModFile($name: String!) {
+	repository(name: $name) {
+		defaultBranch {
+			target {
+				commit {
+					blob(path: "go.mod") {
+						content
+					}
+				}
+			}
+		}
+	}
+}`
+
+func modFileRequest(repository string) graphql.Request {
+	return graphql.Request{Query: modFileQuery, OperationName: "ModFile", Variables: map[string]interface{}{"name": repository}}
+}
@@ -0,0 +1,332 @@
+package goplg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/graphql"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/metrics/prom"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+// Searches of the sources are split to windows, which return all their repositories.
+//
+// SourceGraph is searched in windows of the names of the repositories, starting from the literal
+// prefix of the "repo:^..." filter of the query, e.g. the repositories of github.com/a,
+// github.com/b and so on of "repo:^github\.com/". A search returns at most
+// SOURCEGRAPH_QUERY_PAGE_SIZE repositories, and SourceGraph gives up on a search, which takes too
+// long, so a window, which hits the limit or times out, is split to narrower windows by the next
+// character of the name, until the windows are complete. Server errors of SourceGraph aren't
+// timeouts of a window, they are retried a few times and then fail the fetch.
+//
+// GitHub returns at most 1000 repositories of a search, a page at a time, so it is searched in
+// windows of the creation dates of the repositories, and a window, which has more repositories,
+// is split to halves down to a single day. A query, which has a creation date of its own, isn't
+// split.
+//
+// Repositories are written to the database as the windows are searched, see discover.
+
+// Results of the searches of the windows.
+const (
+	searchComplete  = "complete"
+	searchSplit     = "split"
+	searchTruncated = "truncated"
+)
+
+// SourceGraph
+
+// Characters of the names of the repositories, which the windows are split by. GitHub allows
+// letters, digits and hyphens in the names of the owners, and dots and underscores in the names
+// of the repositories. Windows match the names case-insensitively, so the upper case letters are
+// in the windows of the lower case ones.
+const nameCharacters = "abcdefghijklmnopqrstuvwxyz0123456789-_./"
+
+// Host of the repositories of GitHub, e.g. "github.com/owner/name".
+const gitHubHost = "github.com"
+
+// Longest prefix of a window, the longest name of a repository, see models.CreateRepositoryInput.
+// A window of the longest prefix has a single repository.
+const maxWindowPrefix = 255
+
+// Retries of a search, which fails with a server error of SourceGraph, and the delay before the
+// first retry, which doubles on every retry.
+const sourceGraphRetries = 3
+
+var sourceGraphBackoff = time.Second
+
+// Filter of the names of the repositories in a query of SourceGraph, which the windows start from.
+var repoFilter = regexp.MustCompile(`(?:^|\s)repo:\^(\S+)`)
+
+// Window of the repositories, which names start with the prefix.
+type window struct {
+	prefix string
+}
+
+// Returns the window of the query, which starts from the literal prefix of its "repo:^..."
+// filter, e.g. "github.com/" of "repo:^github\.com/". The filter is left in the query, so a
+// filter, which isn't only a prefix, still applies to the windows. A query without the filter
+// starts from every repository.
+func rootWindow(query string) window {
+	m := repoFilter.FindStringSubmatch(query)
+	if m == nil {
+		return window{}
+	}
+
+	re, err := regexp.Compile(m[1])
+	if err != nil {
+		return window{}
+	}
+
+	prefix, _ := re.LiteralPrefix()
+
+	return window{prefix: prefix}
+}
+
+// Returns the windows of the next character of the names.
+func (w window) split() []window {
+	windows := make([]window, 0, len(nameCharacters))
+
+	for _, c := range nameCharacters {
+		windows = append(windows, window{prefix: w.prefix + string(c)})
+	}
+
+	return windows
+}
+
+// Searches the windows of the query of the source, until the count of the source is found or
+// every window is complete.
+func (g *GoPlugin) searchSourceGraph(d *discovery) error {
+	windows := []window{rootWindow(d.source.Query)}
+
+	for len(windows) > 0 && !d.done() {
+		// Depth first, so the windows waiting are at most the characters of a prefix.
+		w := windows[len(windows)-1]
+		windows = windows[:len(windows)-1]
+
+		repositories, incomplete, err := g.searchWindow(d.source.Query, w, min(g.Config.SourceGraph.QueryPageSize, d.left))
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, len(repositories))
+		for i, r := range repositories {
+			names[i] = r.Name
+		}
+
+		if err := g.discover(d, names); err != nil {
+			return err
+		}
+
+		switch {
+		case incomplete == "" || d.done():
+			prom.DiscoverySearches.WithLabelValues(models.SourceTypeSourceGraph, searchComplete).Inc()
+		case len(w.prefix) >= maxWindowPrefix:
+			prom.DiscoverySearches.WithLabelValues(models.SourceTypeSourceGraph, searchTruncated).Inc()
+
+			g.Logger.Warn("search window can't be split further", logging.KeyStage, config.StageFetch, "source", d.source.Label(), "window", w.prefix, "reason", incomplete, "repositories", len(repositories))
+		default:
+			prom.DiscoverySearches.WithLabelValues(models.SourceTypeSourceGraph, searchSplit).Inc()
+
+			g.Logger.Debug("splitting search window", logging.KeyStage, config.StageFetch, "source", d.source.Label(), "window", w.prefix, "reason", incomplete, "repositories", len(repositories))
+
+			// Reversed, so the windows are searched in the order of the characters.
+			split := w.split()
+			for i := len(split) - 1; i >= 0; i-- {
+				windows = append(windows, split[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reasons of a search of a window, which didn't return every repository of the window.
+const (
+	incompleteLimitHit = "limit_hit"
+	incompleteTimedOut = "timed_out"
+)
+
+// Searches the repositories of the window of the query, up to the count. Returns the reason, if
+// the window has more repositories, or the search timed out, and should be split, and an empty
+// reason, if the window is complete. Partial results of a timeout of SourceGraph are returned, so
+// the repositories are written, before the window is split.
+func (g *GoPlugin) searchWindow(query string, w window, count int) ([]SourceGraphRepositoriesStruct, string, error) {
+	response, err := g.searchWindowRequest(query, w, count)
+	if err != nil {
+		if failures.Classify(err) == failures.ClassTimeout {
+			g.Logger.Debug("search window timed out", logging.KeyStage, config.StageFetch, "window", w.prefix, logging.KeyError, err)
+
+			return nil, incompleteTimedOut, nil
+		}
+
+		return nil, "", err
+	}
+
+	var data SourceGraphDataStruct
+	if err := response.Decode(&data); err != nil {
+		return nil, "", err
+	}
+
+	results := data.Search.Results
+
+	switch {
+	case len(results.Timedout) > 0:
+		// Repositories, which SourceGraph didn't finish searching. LimitHit is set as well.
+		return results.Repositories, incompleteTimedOut, nil
+	case results.LimitHit:
+		return results.Repositories, incompleteLimitHit, nil
+	}
+
+	return results.Repositories, "", nil
+}
+
+// Sends the search of the window, and retries the server errors of SourceGraph with a backoff.
+func (g *GoPlugin) searchWindowRequest(query string, w window, count int) (*graphql.Response, error) {
+	backoff := sourceGraphBackoff
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := g.timeout(context.Background(), config.StageFetch)
+		response, err := g.SourceGraph.Do(ctx, searchRepositoriesRequest(query, w, count))
+		cancel()
+
+		if err == nil || attempt >= sourceGraphRetries || !serverError(err) {
+			return response, err
+		}
+
+		g.Logger.Debug("retrying search window", logging.KeyStage, config.StageFetch, "window", w.prefix, "attempt", attempt+1, logging.KeyError, err)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Returns true, if the status of the error is a server error, e.g. of the gateway of SourceGraph.
+func serverError(err error) bool {
+	var status *failures.StatusError
+
+	return errors.As(err, &status) && status.Status >= http.StatusInternalServerError
+}
+
+// GitHub
+
+// Repositories a search of GitHub returns at most.
+const gitHubSearchLimit = 1000
+
+// Creation date of the oldest repositories of GitHub, which the windows start from.
+var gitHubEpoch = time.Date(2007, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// Window of the repositories created between the days, both included.
+type dateWindow struct {
+	from time.Time
+	to   time.Time
+}
+
+// Returns the qualifier of the window in the search query, e.g. "created:2020-01-01..2020-06-30".
+func (w dateWindow) qualifier() string {
+	return "created:" + w.from.Format(time.DateOnly) + ".." + w.to.Format(time.DateOnly)
+}
+
+// Returns the halves of the window, or false, if the window is a single day.
+func (w dateWindow) split() (dateWindow, dateWindow, bool) {
+	days := int(w.to.Sub(w.from).Hours() / 24)
+	if days < 1 {
+		return w, w, false
+	}
+
+	middle := w.from.AddDate(0, 0, days/2)
+
+	return dateWindow{from: w.from, to: middle}, dateWindow{from: middle.AddDate(0, 0, 1), to: w.to}, true
+}
+
+// Searches the windows of the query of the source a page at a time, until the count of the
+// source is found or every window is complete.
+func (g *GoPlugin) searchGitHub(d *discovery) error {
+	split := !strings.Contains(d.source.Query, "created:")
+
+	windows := []dateWindow{{from: gitHubEpoch, to: time.Now().UTC().Truncate(24 * time.Hour)}}
+
+	for len(windows) > 0 && !d.done() {
+		w := windows[len(windows)-1]
+		windows = windows[:len(windows)-1]
+
+		query := d.source.Query
+		if split {
+			query += " " + w.qualifier()
+		}
+
+		search, err := g.searchGitHubPage(query, "")
+		if err != nil {
+			return err
+		}
+
+		if search.RepositoryCount > gitHubSearchLimit && split {
+			if older, newer, ok := w.split(); ok {
+				prom.DiscoverySearches.WithLabelValues(models.SourceTypeGitHub, searchSplit).Inc()
+
+				g.Logger.Debug("splitting search window", logging.KeyStage, config.StageFetch, "source", d.source.Label(), "window", w.qualifier(), "repositories", search.RepositoryCount)
+
+				// The older half is searched first.
+				windows = append(windows, newer, older)
+
+				continue
+			}
+		}
+
+		for {
+			names := make([]string, 0, len(search.Nodes))
+			for _, r := range search.Nodes {
+				if r.NameWithOwner != "" {
+					names = append(names, gitHubHost+"/"+r.NameWithOwner)
+				}
+			}
+
+			if err := g.discover(d, names); err != nil {
+				return err
+			}
+
+			if !search.PageInfo.HasNextPage || d.done() {
+				break
+			}
+
+			if search, err = g.searchGitHubPage(query, search.PageInfo.EndCursor); err != nil {
+				return err
+			}
+		}
+
+		if search.RepositoryCount > gitHubSearchLimit && !d.done() {
+			prom.DiscoverySearches.WithLabelValues(models.SourceTypeGitHub, searchTruncated).Inc()
+
+			g.Logger.Warn("search window can't be split further", logging.KeyStage, config.StageFetch, "source", d.source.Label(), "query", query, "repositories", search.RepositoryCount)
+
+			continue
+		}
+
+		prom.DiscoverySearches.WithLabelValues(models.SourceTypeGitHub, searchComplete).Inc()
+	}
+
+	return nil
+}
+
+// Searches a page of the repositories of the query, after the cursor of the previous page.
+func (g *GoPlugin) searchGitHubPage(query string, after string) (GitHubSearchStruct, error) {
+	var search GitHubSearchStruct
+
+	ctx, cancel := g.timeout(context.Background(), config.StageFetch)
+	defer cancel()
+
+	response, err := g.GitHub.Do(ctx, searchGitHubRequest(query, after))
+	if err != nil {
+		return search, err
+	}
+
+	err = response.Field("search", &search)
+
+	return search, err
+}
@@ -0,0 +1,252 @@
+package goplg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRootWindow(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{repositoriesSearch, "github.com/"},
+		{`lang:go repo:^github\.com/golang/`, "github.com/golang/"},
+		{`repo:^github\.com/(golang|google)/ lang:go`, "github.com/go"},
+		{`lang:go repo:^gitlab\.com/.*`, "gitlab.com/"},
+		{`lang:go -repo:^github\.com/ repo:^gitlab\.com/`, "gitlab.com/"},
+		{`lang:go repo:(?i)^github`, ""},
+		{`lang:go repo:golang`, ""},
+		{"lang:go", ""},
+	}
+
+	for _, tt := range tests {
+		if got := rootWindow(tt.query); got.prefix != tt.want {
+			t.Errorf("rootWindow(%q) = %q, want %q", tt.query, got.prefix, tt.want)
+		}
+	}
+}
+
+func TestSearchRepositoriesRequest(t *testing.T) {
+	r := searchRepositoriesRequest("lang:go", window{prefix: "github.com/a.b"}, 10)
+
+	if want := `lang:go select:repo repo:(?i)^github\.com/a\.b count:10`; r.Variables["query"] != want {
+		t.Errorf("query = %q, want %q", r.Variables["query"], want)
+	}
+}
+
+func TestSeedRepository(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  string
+		ok    bool
+	}{
+		{"github.com/owner/name", "github.com/owner/name", true},
+		{"https://GitHub.com/owner/name.git", "github.com/owner/name", true},
+		{" owner/name/ ", "github.com/owner/name", true},
+		{"owner", "", false},
+		{"github.com/owner/name/tree/main", "", false},
+		{"github.com//name", "", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := seedRepository(tt.entry); got != tt.want || ok != tt.ok {
+			t.Errorf("seedRepository(%q) = %q, %v, want %q, %v", tt.entry, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// SourceGraph of the repositories, which matches the windows of the searches like SourceGraph,
+// and times out the first search of the window of timeout.
+func newSourceGraph(t *testing.T, repositories []string, timeout string) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	filter := regexp.MustCompile(`repo:\(\?i\)\^(\S*) count:(\d+)$`)
+
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Variables map[string]string `json:"variables"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		query := request.Variables["query"]
+		queries = append(queries, query)
+
+		m := filter.FindStringSubmatch(query)
+		if m == nil || !strings.Contains(query, `repo:^github\.com/ `) {
+			t.Errorf("query %q has no window", query)
+			return
+		}
+
+		prefix := strings.ToLower(strings.ReplaceAll(m[1], `\`, ""))
+		count, _ := strconv.Atoi(m[2])
+
+		type repository struct {
+			Name string `json:"name"`
+		}
+
+		var results struct {
+			LimitHit     bool         `json:"limitHit"`
+			Timedout     []repository `json:"timedout"`
+			Repositories []repository `json:"repositories"`
+		}
+
+		for _, name := range repositories {
+			if !strings.HasPrefix(strings.ToLower(name), prefix) {
+				continue
+			}
+
+			switch {
+			case prefix == timeout:
+				// Partial results of the first search of the window.
+				timeout = ""
+				results.LimitHit = true
+				results.Timedout = []repository{{Name: name}}
+			case len(results.Repositories) == count:
+				results.LimitHit = true
+			default:
+				results.Repositories = append(results.Repositories, repository{Name: name})
+				continue
+			}
+
+			break
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"search": map[string]interface{}{"results": results}}})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &queries
+}
+
+func TestSearchSourceGraph(t *testing.T) {
+	var repositories []string
+
+	for _, owner := range []string{"a", "ab", "B", "b-c", "c.d", "c_e", "Ce"} {
+		for i := 0; i < 12; i++ {
+			repositories = append(repositories, fmt.Sprintf("github.com/%s/r%d", owner, i))
+		}
+	}
+
+	server, queries := newSourceGraph(t, repositories, "github.com/c")
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.Config{SourceGraph: config.SourceGraph{GraphQlApiBaseUrl: server.URL, QueryPageSize: 10}}
+
+	if err := NewGoPlugin(db, c, 0).Fetch(len(repositories)*2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	db.Model(&models.Repository{}).Pluck("repository_name", &names)
+
+	if len(names) != len(repositories) {
+		t.Errorf("repositories = %d, want %d, after %d searches", len(names), len(repositories), len(*queries))
+	}
+
+	timedOut := 0
+
+	for _, q := range *queries {
+		if strings.Contains(q, `repo:(?i)^github\.com/c count:`) {
+			timedOut++
+		}
+	}
+
+	if timedOut != 1 {
+		t.Errorf("searches of the window, which timed out = %d, want 1 before it is split", timedOut)
+	}
+}
+
+func TestFetchEnrichedRepositories(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enrich replaced the names with the short names of GitHub.
+	db.Create(&[]models.Repository{
+		{RepositoryName: "name", RepositoryUrl: "github.com/a/name"},
+		{RepositoryName: "name", RepositoryUrl: "github.com/b/name"},
+	})
+
+	seeds := models.Source{Type: models.SourceTypeSeeds, Repositories: []string{"a/name", "https://github.com/b/name", "c/name"}}
+
+	if err := NewGoPlugin(db, &config.Config{}, 0).Fetch(10, []models.Source{seeds}); err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	db.Model(&models.Repository{}).Order("id").Pluck("repository_url", &urls)
+
+	if want := []string{"github.com/a/name", "github.com/b/name", "github.com/c/name"}; strings.Join(urls, " ") != strings.Join(want, " ") {
+		t.Errorf("repositories = %v, want %v", urls, want)
+	}
+}
+
+func TestSearchSourceGraphUnavailable(t *testing.T) {
+	sourceGraphBackoff = time.Millisecond
+	t.Cleanup(func() { sourceGraphBackoff = time.Second })
+
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.Config{SourceGraph: config.SourceGraph{GraphQlApiBaseUrl: server.URL, QueryPageSize: 10}}
+
+	var status *failures.StatusError
+
+	if err := NewGoPlugin(db, c, 0).Fetch(10, nil); !errors.As(err, &status) || status.Status != http.StatusServiceUnavailable {
+		t.Errorf("error = %v, want the status of SourceGraph", err)
+	}
+
+	// The window isn't split.
+	if requests != sourceGraphRetries+1 {
+		t.Errorf("requests = %d, want %d", requests, sourceGraphRetries+1)
+	}
+}
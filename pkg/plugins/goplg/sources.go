@@ -0,0 +1,143 @@
+package goplg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/logging"
+	"github.com/haapjari/glass/pkg/models"
+)
+
+// Sources of the repositories, see models.Source. Every source discovers up to the count of new
+// repositories, in the order of the sources. A repository, which an earlier source, or an earlier
+// fetch found, is skipped, so it keeps the tag it was found with.
+
+// Sources of a fetch without sources.
+var DefaultSources = []models.Source{{Type: models.SourceTypeSourceGraph, Query: repositoriesSearch}}
+
+// Repositories of a source, which are written to the database, as they are found.
+type discovery struct {
+	source models.Source
+
+	// New repositories left to find.
+	left int
+
+	// Names of the repositories, which were found by the fetch, or are in the database.
+	seen map[string]bool
+}
+
+func (d *discovery) done() bool {
+	return d.left <= 0
+}
+
+// Discovers the repositories of the source, and writes them to the database.
+func (g *GoPlugin) fetchSource(source models.Source, count int, seen map[string]bool) error {
+	d := &discovery{source: source, left: count, seen: seen}
+
+	var err error
+
+	switch source.Type {
+	case models.SourceTypeSourceGraph:
+		err = g.searchSourceGraph(d)
+	case models.SourceTypeGitHub:
+		err = g.searchGitHub(d)
+	case models.SourceTypeSeeds:
+		err = g.discover(d, g.seedRepositories(source))
+	default:
+		err = fmt.Errorf("unsupported source type %q", source.Type)
+	}
+
+	if err != nil {
+		return fmt.Errorf("source %s: %w", source.Label(), err)
+	}
+
+	g.Logger.Info("repositories found", logging.KeyStage, config.StageFetch, "source", source.Label(), "repositories", count-d.left)
+
+	return nil
+}
+
+// Writes the repositories, which weren't seen yet, up to the repositories left, tagged with the
+// source.
+func (g *GoPlugin) discover(d *discovery, names []string) error {
+	var fresh []string
+
+	for _, name := range names {
+		if d.seen[name] || len(fresh) >= d.left {
+			continue
+		}
+
+		d.seen[name] = true
+		fresh = append(fresh, name)
+	}
+
+	if err := g.writeRepositoriesToDatabase(fresh, d.source.Label()); err != nil {
+		return err
+	}
+
+	d.left -= len(fresh)
+
+	return nil
+}
+
+// Returns the names of the repositories of the seed list, e.g. "github.com/owner/name". Entries,
+// which aren't repositories, are skipped.
+func (g *GoPlugin) seedRepositories(source models.Source) []string {
+	var names []string
+
+	for _, entry := range source.Repositories {
+		name, ok := seedRepository(entry)
+		if !ok {
+			g.Logger.Warn("skipping an invalid seed repository", logging.KeyStage, config.StageFetch, "source", source.Label(), logging.KeyRepository, entry)
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// Parses an entry of a seed list to the name of the repository: "github.com/owner/name",
+// "https://github.com/owner/name.git" and "owner/name" are "github.com/owner/name", see
+// models.SeedRepository.
+func seedRepository(entry string) (string, bool) {
+	if strings.Count(strings.Trim(strings.TrimSpace(entry), "/"), "/") == 1 {
+		entry = gitHubHost + "/" + strings.Trim(strings.TrimSpace(entry), "/")
+	}
+
+	return models.SeedRepository(entry)
+}
+
+// Returns the names of the repositories in the database, see discoveredName.
+func (g *GoPlugin) repositoryNameSet() (map[string]bool, error) {
+	var repositories []models.Repository
+
+	if err := g.DatabaseClient.Select("repository_name", "repository_url").Find(&repositories).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(repositories))
+
+	for _, r := range repositories {
+		seen[discoveredName(r)] = true
+	}
+
+	return seen, nil
+}
+
+// Returns the name the repository was discovered with, e.g. "github.com/owner/name". Enrich
+// replaces the repository_name with the short name of GitHub, so the name is read from the URL,
+// and from the repository_name only without an URL.
+func discoveredName(r models.Repository) string {
+	url := r.RepositoryUrl
+	if url == "" {
+		url = r.RepositoryName
+	}
+
+	if name, ok := seedRepository(url); ok {
+		return name
+	}
+
+	return url
+}
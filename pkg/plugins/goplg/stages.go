@@ -0,0 +1,91 @@
+package goplg
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Limits of the stages, see config.Stage.
+
+// Returns a context, which expires after the timeout of the stage.
+func (g *GoPlugin) timeout(ctx context.Context, stage string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, g.Config.Stage(stage).Timeout)
+}
+
+// Reads the repositories from the database in batches of the stage, and calls fn with each
+// batch. The batch is reused, so fn must not keep it. Stops at the first error of fn. When the
+// stage is retried, only the repositories of the retry are read.
+func (g *GoPlugin) inBatches(stage string, fn func(repositories []models.Repository) error) error {
+	var batch []models.Repository
+
+	tx := g.DatabaseClient
+	if len(g.only) > 0 {
+		tx = tx.Where("repository_name IN ?", g.only)
+	}
+
+	return tx.FindInBatches(&batch, g.Config.Stage(stage).BatchSize, func(tx *gorm.DB, n int) error {
+		return fn(batch)
+	}).Error
+}
+
+// Splits the items to batches of the size.
+func batches[T any](items []T, size int) [][]T {
+	var b [][]T
+
+	for size < len(items) {
+		items, b = items[size:], append(b, items[:size])
+	}
+
+	if len(items) > 0 {
+		b = append(b, items)
+	}
+
+	return b
+}
+
+// Disk space budget of the working directory of a stage.
+type diskBudget struct {
+	dir   string
+	limit int64
+}
+
+func (g *GoPlugin) diskBudget(stage string, dir string) diskBudget {
+	return diskBudget{dir: dir, limit: g.Config.Stage(stage).DiskBudget}
+}
+
+// Returns true, if the directory uses the budget. Zero budget is never exceeded.
+func (d diskBudget) exceeded() bool {
+	return d.limit > 0 && dirSize(d.dir) >= d.limit
+}
+
+// Waits until the directory is within the budget, as long as busy returns true. The work in
+// progress frees the disk space, when it finishes.
+func (d diskBudget) wait(busy func() bool) {
+	for d.exceeded() && busy() {
+		time.Sleep(time.Second)
+	}
+}
+
+// Returns the size of the files in the directory. Missing directory is empty.
+func dirSize(dir string) int64 {
+	var size int64
+
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
@@ -1,70 +1,65 @@
 package goplg
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"os/exec"
 	"strings"
-	"sync"
+	"time"
 	"unicode"
 
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/failures"
+	"github.com/haapjari/glass/pkg/metrics/prom"
 	"github.com/haapjari/glass/pkg/models"
-	"github.com/haapjari/glass/pkg/utils"
+	"github.com/haapjari/glass/pkg/pool"
 	"github.com/hhatto/gocloc"
-	JSONParser "github.com/tidwall/gjson"
 )
 
-func (g *GoPlugin) writeSourceGraphResponseToDatabase(length int, repositories []SourceGraphRepositoriesStruct) {
-	var wg sync.WaitGroup
+// Writes the repositories tagged with the source in batches of the fetch stage.
+func (g *GoPlugin) writeRepositoriesToDatabase(names []string, source string) error {
+	stage := g.Config.Stage(config.StageFetch)
 
-	// Semaphore is a safeguard to goroutines, to allow only "MaxThreads" run at the same time.
-	semaphore := make(chan int, g.MaxThreads)
-
-	for i := 0; i < length; i++ {
-		semaphore <- 1
-		wg.Add(1)
+	errs := pool.Each(context.Background(), stage.Workers, batches(names, stage.BatchSize), func(ctx context.Context, batch []string) error {
+		r := make([]models.Repository, len(batch))
+		for i, name := range batch {
+			r[i] = models.Repository{RepositoryName: name, RepositoryUrl: name, DiscoverySource: source}
+		}
 
-		go func(i int) {
-			r := models.Repository{RepositoryName: repositories[i].Name, RepositoryUrl: repositories[i].Name, OpenIssueCount: "", ClosedIssueCount: "", OriginalCodebaseSize: "", LibraryCodebaseSize: "", RepositoryType: "", PrimaryLanguage: ""}
-			g.DatabaseClient.Create(&r)
+		if err := g.DatabaseClient.Create(&r).Error; err != nil {
+			return err
+		}
 
-			defer func() { <-semaphore }()
-		}(i)
-		wg.Done()
-	}
-	wg.Wait()
+		prom.RepositoriesDiscovered.WithLabelValues(Name).Add(float64(len(r)))
 
-	// When the Channel Length is not 0, there is still running goroutines.
-	for !(len(semaphore) == 0) {
-		continue
-	}
+		return nil
+	})
 
+	return errors.Join(errs...)
 }
 
-// TODO: Refactor, to not to use HTTP requests (?)
-func (g *GoPlugin) getAllRepositories() models.RepositoryResponse {
-	getRepositoriesRequest, err := http.NewRequest("GET", REPOSITORY_API_BASEURL, nil)
-	utils.CheckErr(err)
-
-	getRepositoriesRequest.Header.Set("Content-Type", "application/json")
+// Reads all the repositories from the database. The list endpoint of the API is paginated,
+// so the database is read directly.
+func (g *GoPlugin) getAllRepositories() (models.RepositoryResponse, error) {
+	var repositories models.RepositoryResponse
 
-	getRepositoriesResponse, err := g.HttpClient.Do(getRepositoriesRequest)
-	utils.CheckErr(err)
+	err := g.DatabaseClient.Order("id").Find(&repositories.RepositoryData).Error
 
-	defer getRepositoriesResponse.Body.Close()
+	return repositories, err
+}
 
-	getRepositoriesResponseBody, err := ioutil.ReadAll(getRepositoriesResponse.Body)
-	utils.CheckErr(err)
+// Returns the names of the repositories.
+func repositoryNames(repositories []models.Repository) []string {
+	names := make([]string, len(repositories))
 
-	var repositories models.RepositoryResponse
-	json.Unmarshal([]byte(getRepositoriesResponseBody), &repositories)
+	for i, r := range repositories {
+		names[i] = r.RepositoryName
+	}
 
-	return repositories
+	return names
 }
 
 // Filter empty strings from slice.
@@ -79,23 +74,42 @@ func filterEmpty(slice []string) []string {
 }
 
 // Performs a GET request to the specified URL.
-func performGetRequest(url string) string {
+func (g *GoPlugin) performGetRequest(ctx context.Context, url string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
 	// Make a GET request to the specified URL
-	resp, err := http.Get(url)
-	utils.CheckErr(err)
+	resp, err := g.HttpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
 
 	defer resp.Body.Close()
 
 	// Read the response body into a variable
+	body, err := readResponse(resp)
+
+	return string(body), err
+}
+
+// Reads the body of the response. Returns an error, if the status isn't successful.
+func readResponse(resp *http.Response) ([]byte, error) {
 	body, err := ioutil.ReadAll(resp.Body)
-	utils.CheckErr(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &failures.StatusError{Url: resp.Request.URL.String(), Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
 
-	return (string(body))
+	return body, nil
 }
 
-// Calculates the lines of code using https://github.com/hhatto/gocloc
-// in the path provided and return the value.
-func runGocloc(path string) int {
+// CodeLines calculates the lines of code in the path with gocloc.
+func CodeLines(path string) (int, error) {
 	languages := gocloc.NewDefinedLanguages()
 	options := gocloc.NewClocOptions()
 
@@ -105,76 +119,44 @@ func runGocloc(path string) int {
 
 	processor := gocloc.NewProcessor(languages, options)
 
+	start := time.Now()
 	result, err := processor.Analyze(paths)
-	utils.CheckErr(err)
+	prom.GoclocDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return 0, err
+	}
 
-	return int(result.Total.Code)
+	return int(result.Total.Code), nil
 }
 
-// Wrapper for "exec/os" command execution.
-// Copied from blog: https://blog.kowalczyk.info/article/wOYk/advanced-command-execution-in-go-with-osexec.html
-func runCommand(name string, arg ...string) (string, string) {
-	cmd := exec.Command(name, arg...)
+// Runs the command of the repository in the stage, and returns its output, the standard output
+// and the standard error combined. The command is killed, when the context expires. The output
+// is kept by the outputs of the plugin, instead of printing it, and the error of a failed command
+// ends with the last line of the output.
+func (g *GoPlugin) runCommand(ctx context.Context, stage string, repository string, name string, arg ...string) (string, error) {
+	command := name + " " + strings.Join(arg, " ")
 
-	var stdout, stderr []byte
-	var errStdout, errStderr error
+	out, err := exec.CommandContext(ctx, name, arg...).CombinedOutput()
 
-	stdoutIn, _ := cmd.StdoutPipe()
-	stderrIn, _ := cmd.StderrPipe()
+	output := string(out)
 
-	err := cmd.Start()
-	utils.CheckErr(err)
-
-	// WaitGroup ensures, cmd.Wait() is called, after we finish reading from stdin and stdout.
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	go func() {
-		stdout, errStdout = copyAndCapture(os.Stdout, stdoutIn)
-		wg.Done()
-	}()
-
-	stderr, errStderr = copyAndCapture(os.Stderr, stderrIn)
-
-	wg.Wait()
-
-	err = cmd.Wait()
-	if err != nil {
-		log.Fatalf("cmd.Run() failed with %s\n", err)
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("%s: %w", command, ctx.Err())
+	case err != nil:
+		err = fmt.Errorf("%s: %w: %s", command, err, lastLine(output))
 	}
 
-	if errStdout != nil || errStderr != nil {
-		log.Fatal("failed to capture stdout or stderr\n")
-	}
-
-	outStr, errStr := string(stdout), string(stderr)
+	g.Outputs.Record(stage, repository, command, output, err)
 
-	return outStr, errStr
+	return output, err
 }
 
-// Helper function for running commands with "os/exec".
-// Copied from blog: https://blog.kowalczyk.info/article/wOYk/advanced-command-execution-in-go-with-osexec.html
-func copyAndCapture(w io.Writer, r io.Reader) ([]byte, error) {
-	var out []byte
-	buf := make([]byte, 1024, 1024)
-	for {
-		n, err := r.Read(buf[:])
-		if n > 0 {
-			d := buf[:n]
-			out = append(out, d...)
-			_, err := w.Write(d)
-			if err != nil {
-				return out, err
-			}
-		}
-		if err != nil {
-			// Read returns io.EOF at the end of file, which is not an error for us
-			if err == io.EOF {
-				err = nil
-			}
-			return out, err
-		}
-	}
+// Returns the last non-empty line of the output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	return strings.TrimSpace(lines[len(lines)-1])
 }
 
 // removeDuplicates removes duplicates from a slice of strings
@@ -207,7 +189,8 @@ func parseRepositoryName(s string) (string, string, error) {
 	return parts[1], parts[2], nil
 }
 
-// Creates a slice of repositories, which are duplicates in an original list.
+// Creates a slice of repositories, which are duplicates of an earlier repository in an original
+// list, by the names they were discovered with, see discoveredName.
 func findDuplicateRepositoryEntries(repositories []models.Repository) []models.Repository {
 	// Create a map to store the names of the repositories that we've seen so far
 	seenRepositories := make(map[string]bool)
@@ -218,36 +201,17 @@ func findDuplicateRepositoryEntries(repositories []models.Repository) []models.R
 	// Iterate through the slice of repositories
 	for _, repository := range repositories {
 		// If we've already seen this repository, add it to the slice of duplicate entries
-		if seenRepositories[repository.RepositoryName] {
+		if seenRepositories[discoveredName(repository)] {
 			duplicateEntries = append(duplicateEntries, repository)
 		} else {
 			// Otherwise, mark the repository as seen
-			seenRepositories[repository.RepositoryName] = true
+			seenRepositories[discoveredName(repository)] = true
 		}
 	}
 
 	return duplicateEntries
 }
 
-// Check if a folder exists in the file system.
-func folderExists(folderPath string) bool {
-	// Use os.Stat to get the file information for the folder
-	_, err := os.Stat(folderPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// The folder does not exist
-			return false
-		} else {
-			// Some other error occurred
-			fmt.Printf("Error checking if folder exists: %v", err)
-			return false
-		}
-	}
-
-	// The folder exists
-	return true
-}
-
 // Parse "github.com/mholt/archiver/v3 v3.5.1" into the format "github.com/mholt/archiver/v3@v3.5.1"
 func parseUrlToDownloadFormat(input string) string {
 	// Split the input string on the first space character
@@ -297,13 +261,3 @@ func parseGoLibraryUrl(input string) string {
 	// Return the modified package name followed by an '@' symbol and the version
 	return packageName + "@" + parts[1]
 }
-
-// Export the JSON Parser to separate function.
-func extractDefaultBranchCommitBlobContent(sourceGraphResponseBody []byte) string {
-	outerModFile := JSONParser.Get(
-		string(sourceGraphResponseBody),
-		"data.repository.defaultBranch.target.commit.blob.content",
-	)
-
-	return outerModFile.String()
-}
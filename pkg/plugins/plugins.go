@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/plugins/goplg"
+	"gorm.io/gorm"
+)
+
+// Plugin collects the repositories of a language in stages. Each stage reads the repositories
+// from the database, and writes the results back, so the stages can be run separately. A
+// repository, which fails in a stage, is recorded as a failure, and the stage continues with the
+// other repositories. The stages return errors, which stop the whole stage.
+type Plugin interface {
+	// Fetch discovers count new repositories from each source, and writes them to the database
+	// tagged with the source. Without sources, the default search of the plugin is used.
+	Fetch(count int, sources []models.Source) error
+
+	// Enrich appends the metadata of the repositories.
+	Enrich() error
+
+	// Measure calculates the codebase sizes of the repositories.
+	Measure() error
+
+	// Retry runs the stage again for the repositories, which failed in it.
+	Retry(stage string, repositories []string) error
+}
+
+// Names of the available plugins.
+var Names = []string{goplg.Name}
+
+// Names of the plugins by the primary language of the repositories they collect.
+var Languages = map[string]string{goplg.Language: goplg.Name}
+
+// ErrUnsupported is returned for a plugin name, which doesn't exist.
+var ErrUnsupported = errors.New("unsupported plugin")
+
+// New returns the plugin of the name, e.g. "go". Job is the id of the job, which runs the plugin,
+// and zero outside jobs. Returns an error, if the configuration lacks values the plugin needs.
+func New(name string, db *gorm.DB, c *config.Config, job int) (Plugin, error) {
+	switch name {
+	case goplg.Name:
+		if err := c.Validate(goplg.Requirements...); err != nil {
+			return nil, err
+		}
+
+		return goplg.NewGoPlugin(db, c, job), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+}
+
+// Requirements returns the requirements of the configuration of the plugin.
+func Requirements(name string) ([]config.Requirement, error) {
+	switch name {
+	case goplg.Name:
+		return goplg.Requirements, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+}
+
+// Run runs every stage of the plugin. Stops at the first stage, which returns an error.
+func Run(p Plugin, count int, sources []models.Source) error {
+	if err := p.Fetch(count, sources); err != nil {
+		return err
+	}
+
+	if err := p.Enrich(); err != nil {
+		return err
+	}
+
+	return p.Measure()
+}
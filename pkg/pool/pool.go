@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Bounded worker pool of the pipeline stages. Items are handed to a fixed amount of goroutines,
+// and every result is written to its own index, so the callers don't need locks or semaphores.
+// A failing item doesn't stop the others.
+
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Map calls fn for every item with at most workers goroutines, and returns the results in the
+// order of the items. When the context is cancelled, the items, which were not started, fail
+// with the error of the context.
+func Map[I any, O any](ctx context.Context, workers int, items []I, fn func(ctx context.Context, item I) (O, error)) []Result[O] {
+	results := make([]Result[O], len(items))
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+
+				results[i].Value, results[i].Err = fn(ctx, items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// Each calls fn for every item with at most workers goroutines, and returns the errors in the
+// order of the items. Items, which succeeded, have a nil error.
+func Each[I any](ctx context.Context, workers int, items []I, fn func(ctx context.Context, item I) error) []error {
+	results := Map(ctx, workers, items, func(ctx context.Context, item I) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	})
+
+	errs := make([]error, len(results))
+
+	for i, r := range results {
+		errs[i] = r.Err
+	}
+
+	return errs
+}
@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func items(n int) []int {
+	items := make([]int, n)
+
+	for i := range items {
+		items[i] = i
+	}
+
+	return items
+}
+
+func TestMapOrder(t *testing.T) {
+	for _, workers := range []int{-1, 0, 1, 3, 100} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			results := Map(context.Background(), workers, items(50), func(ctx context.Context, item int) (string, error) {
+				// Later items finish first.
+				time.Sleep(time.Duration(50-item) * 10 * time.Microsecond)
+
+				return fmt.Sprint(item), nil
+			})
+
+			if len(results) != 50 {
+				t.Fatalf("results = %d, want 50", len(results))
+			}
+
+			for i, r := range results {
+				if r.Err != nil || r.Value != fmt.Sprint(i) {
+					t.Errorf("result %d = %+v", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestMapEmpty(t *testing.T) {
+	results := Map(context.Background(), 5, nil, func(ctx context.Context, item int) (int, error) {
+		t.Error("fn called without items")
+		return 0, nil
+	})
+
+	if len(results) != 0 {
+		t.Errorf("results = %v, want none", results)
+	}
+}
+
+func TestEachErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	errs := Each(context.Background(), 4, items(20), func(ctx context.Context, item int) error {
+		if item%2 == 1 {
+			return fmt.Errorf("item %d: %w", item, errOdd)
+		}
+
+		return nil
+	})
+
+	for i, err := range errs {
+		if odd := i%2 == 1; odd != errors.Is(err, errOdd) {
+			t.Errorf("error of item %d = %v", i, err)
+		}
+	}
+}
+
+func TestMapWorkers(t *testing.T) {
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			var running, most atomic.Int32
+
+			Each(context.Background(), workers, items(64), func(ctx context.Context, item int) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					m := most.Load()
+					if n <= m || most.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				time.Sleep(time.Millisecond)
+
+				return nil
+			})
+
+			if m := most.Load(); m > int32(workers) || m < 1 {
+				t.Errorf("items run at once = %d, want at most %d", m, workers)
+			}
+		})
+	}
+}
+
+func TestMapCancel(t *testing.T) {
+	const workers = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var cancelled atomic.Bool
+	var late atomic.Int32
+
+	started := make([]atomic.Bool, 20)
+
+	errs := Each(ctx, workers, items(20), func(ctx context.Context, item int) error {
+		started[item].Store(true)
+
+		if cancelled.Load() {
+			late.Add(1)
+		}
+
+		if item == 3 {
+			cancel()
+			cancelled.Store(true)
+		}
+
+		return nil
+	})
+
+	// Items, which the workers had taken, when the context was cancelled, finish. Every other
+	// item fails without calling fn.
+	for i, err := range errs {
+		switch {
+		case started[i].Load() && err != nil:
+			t.Errorf("error of started item %d = %v, want none", i, err)
+		case !started[i].Load() && !errors.Is(err, context.Canceled):
+			t.Errorf("error of item %d = %v, want the context", i, err)
+		}
+	}
+
+	if n := late.Load(); n > workers-1 {
+		t.Errorf("started %d items after the cancel, want at most %d", n, workers-1)
+	}
+}
@@ -0,0 +1,216 @@
+package quality
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/version"
+)
+
+// Calculates the Quality Measure of the repositories.
+//
+// Every factor is scored between 0 and 5 by the rank of the value within the dataset, so 2.5 is
+// the middle of the values. Quality Measure is the average of the factors, which have a value.
+// Changes to the factors or to the scoring require a new version.QualityMeasureVersion.
+
+const MaxScore = 5.0
+
+const (
+	FactorActivity      = "activity"
+	FactorIssueRatio    = "issue_ratio"
+	FactorMaturity      = "maturity"
+	FactorPopularity    = "popularity"
+	FactorLatestRelease = "latest_release"
+)
+
+type Factor struct {
+	Name        string
+	Description string
+
+	// Higher values are better, unless set.
+	LowerIsBetter bool
+
+	// Returns the value of the factor, and false, if the repository doesn't have a value.
+	Value func(r models.Repository) (float64, bool)
+}
+
+var Factors = []Factor{
+	{
+		Name:        FactorActivity,
+		Description: "Amount of commits. Higher is better.",
+		Value:       count(func(r models.Repository) string { return r.CommitCount }),
+	},
+	{
+		Name:          FactorIssueRatio,
+		Description:   "Ratio of open issues to closed issues. Lower is better.",
+		LowerIsBetter: true,
+		Value:         IssueRatio,
+	},
+	{
+		Name:          FactorMaturity,
+		Description:   "Creation date. Older is better.",
+		LowerIsBetter: true,
+		Value:         date(func(r models.Repository) string { return r.CreationDate }),
+	},
+	{
+		Name:        FactorPopularity,
+		Description: "Amount of stars. Higher is better.",
+		Value:       count(func(r models.Repository) string { return r.StargazerCount }),
+	},
+	{
+		Name:        FactorLatestRelease,
+		Description: "Date of the latest release. More recent is better.",
+		Value:       date(func(r models.Repository) string { return r.LatestRelease }),
+	},
+}
+
+// Columns of the repositories, which the factors read.
+var Columns = []string{"repository_name", "commit_count", "open_issue_count", "closed_issue_count", "creation_date", "stargazer_count", "latest_release"}
+
+type Score struct {
+	RepositoryName        string             `json:"repository_name"`
+	QualityMeasure        float64            `json:"quality_measure"`
+	QualityMeasureVersion string             `json:"quality_measure_version"`
+	Factors               map[string]float64 `json:"factors"`
+}
+
+// Measure scores the repositories against each other. Scores are returned in the order of the repositories.
+func Measure(repositories []models.Repository) []Score {
+	scores := make([]Score, len(repositories))
+
+	for i, r := range repositories {
+		scores[i] = Score{
+			RepositoryName:        r.RepositoryName,
+			QualityMeasureVersion: version.QualityMeasureVersion,
+			Factors:               make(map[string]float64),
+		}
+	}
+
+	for _, f := range Factors {
+		var (
+			indexes []int
+			values  []float64
+		)
+
+		for i, r := range repositories {
+			if v, ok := f.Value(r); ok {
+				indexes = append(indexes, i)
+				values = append(values, v)
+			}
+		}
+
+		for j, rank := range ranks(values) {
+			if f.LowerIsBetter {
+				rank = 1 - rank
+			}
+
+			scores[indexes[j]].Factors[f.Name] = round(rank * MaxScore)
+		}
+	}
+
+	for i := range scores {
+		scores[i].QualityMeasure = average(scores[i].Factors)
+	}
+
+	return scores
+}
+
+// IssueRatio is the ratio of open issues to closed issues. Repositories without closed issues have no ratio.
+func IssueRatio(r models.Repository) (float64, bool) {
+	open, ok := parseCount(r.OpenIssueCount)
+	if !ok {
+		return 0, false
+	}
+
+	closed, ok := parseCount(r.ClosedIssueCount)
+	if !ok || closed == 0 {
+		return 0, false
+	}
+
+	return open / closed, true
+}
+
+// Returns the percentile ranks of the values between 0 and 1. Equal values have the same rank.
+// A single value is ranked in the middle.
+func ranks(values []float64) []float64 {
+	n := len(values)
+
+	if n == 1 {
+		return []float64{0.5}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	r := make([]float64, n)
+
+	for i := 0; i < n; {
+		// Ties share the average of their positions.
+		j := i
+		for j+1 < n && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+
+		rank := float64(i+j) / 2 / float64(n-1)
+
+		for k := i; k <= j; k++ {
+			r[order[k]] = rank
+		}
+
+		i = j + 1
+	}
+
+	return r
+}
+
+func count(column func(r models.Repository) string) func(r models.Repository) (float64, bool) {
+	return func(r models.Repository) (float64, bool) {
+		return parseCount(column(r))
+	}
+}
+
+// Dates are compared as Unix time.
+func date(column func(r models.Repository) string) func(r models.Repository) (float64, bool) {
+	return func(r models.Repository) (float64, bool) {
+		t, err := time.Parse(time.RFC3339, column(r))
+		if err != nil {
+			return 0, false
+		}
+
+		return float64(t.Unix()), true
+	}
+}
+
+func parseCount(s string) (float64, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	return float64(n), true
+}
+
+func average(factors map[string]float64) float64 {
+	if len(factors) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+
+	for _, v := range factors {
+		sum += v
+	}
+
+	return round(sum / float64(len(factors)))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
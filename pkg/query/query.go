@@ -0,0 +1,548 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haapjari/glass/pkg/apierror"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Translates the query parameters of the list endpoints into database queries.
+//
+//	Filtering:  ?primary_language=Go&stargazer_count>=100&license_info!=mit&created_before=2020-01-01
+//	Sorting:    ?sort=-stargazer_count,repository_name
+//	Fields:     ?fields=repository_name,stargazer_count
+//	Pagination: ?limit=50&offset=100 or ?limit=50&cursor=<next_cursor>
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 10000
+)
+
+// Parameters, which are never treated as filters. Every other parameter must be a filter of a
+// column, so a misspelled filter is an error, instead of being left out, e.g. of a bulk delete.
+var reserved = map[string]bool{
+	"limit":   true,
+	"offset":  true,
+	"cursor":  true,
+	"sort":    true,
+	"fields":  true,
+	"columns": true,
+	"all":     true,
+}
+
+// Resource describes a model, which can be queried.
+type Resource struct {
+	Model interface{}
+
+	// Columns, which store integers as strings. These are filtered and sorted numerically.
+	Numeric []string
+
+	// Prefixes of the date range filters and their columns. For example "created" -> "creation_date"
+	// enables "created_before" and "created_after" filters.
+	Ranges map[string]string
+}
+
+type Query struct {
+	Fields []string
+	Limit  int
+	Offset int
+
+	resource Resource
+	columns  map[string]bool
+	filters  []filter
+	orders   []order
+	cursor   []string
+}
+
+type filter struct {
+	column   string
+	operator string
+	values   []string
+}
+
+type order struct {
+	column string
+	desc   bool
+}
+
+type Meta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+}
+
+// Parse reads filters, sorting, field selection and pagination from the query parameters.
+func Parse(c *gin.Context, r Resource) (*Query, error) {
+	q := new(Query)
+
+	q.resource = r
+	q.columns = make(map[string]bool)
+
+	for _, column := range models.Columns(r.Model) {
+		q.columns[column] = true
+	}
+
+	values := c.Request.URL.Query()
+
+	if err := q.parseFilters(values); err != nil {
+		return nil, apierror.BadRequest("%s", err)
+	}
+
+	if err := q.parseSort(values.Get("sort")); err != nil {
+		return nil, apierror.BadRequest("%s", err)
+	}
+
+	if err := q.parseFields(values.Get("fields")); err != nil {
+		return nil, apierror.BadRequest("%s", err)
+	}
+
+	if err := q.parsePagination(values); err != nil {
+		return nil, apierror.BadRequest("%s", err)
+	}
+
+	return q, nil
+}
+
+// Filter is a GORM scope, which applies only the filters of the query.
+func (q *Query) Filter(db *gorm.DB) *gorm.DB {
+	for _, f := range q.filters {
+		expr := q.expr(f.column)
+
+		switch {
+		case f.operator == "=" && len(f.values) > 1:
+			db = db.Where(expr+" IN ?", q.args(f.column, f.values))
+		case f.operator == "=" || f.operator == "!=":
+			db = db.Where(expr+" "+sqlOperator(f.operator)+" ?", q.arg(f.column, f.values[0]))
+		default:
+			// Empty values are not comparable, e.g. a repository without a creation date.
+			if !q.isNumeric(f.column) {
+				db = db.Where(f.column + " <> ''")
+			}
+
+			db = db.Where(expr+" "+f.operator+" ?", q.arg(f.column, f.values[0]))
+		}
+	}
+
+	return db
+}
+
+// HasFilters reports, if the query narrows the rows with any filter.
+func (q *Query) HasFilters() bool {
+	return len(q.filters) > 0
+}
+
+// List writes a page of the resource to the response, with the total count and links to the next page.
+func List[T any](c *gin.Context, db *gorm.DB, r Resource) {
+	q, err := Parse(c, r)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	var total int64
+
+	if err := db.Model(new(T)).Scopes(q.Filter).Count(&total).Error; err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	var rows []T
+
+	if err := db.Scopes(q.Filter, q.page).Find(&rows).Error; err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	meta := Meta{Total: total, Limit: q.Limit, Offset: q.Offset}
+	links := Links{Self: c.Request.URL.RequestURI()}
+
+	// One row more than the limit is fetched, to know if there is a next page.
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+
+		meta.NextCursor = q.encodeCursor(&rows[len(rows)-1])
+		links.Next = q.nextLink(c.Request.URL, meta.NextCursor)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": q.project(rows), "meta": meta, "links": links})
+}
+
+// Applies the cursor, sorting, field selection and pagination to the query.
+func (q *Query) page(db *gorm.DB) *gorm.DB {
+	if q.cursor != nil {
+		condition, args := q.keyset()
+		db = db.Where(condition, args...)
+	}
+
+	for _, o := range q.sortOrders() {
+		direction := " ASC"
+		if o.desc {
+			direction = " DESC"
+		}
+
+		db = db.Order(q.expr(o.column) + direction)
+	}
+
+	if q.Fields != nil {
+		db = db.Select(q.selectedColumns())
+	}
+
+	if q.cursor == nil && q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+
+	return db.Limit(q.Limit + 1)
+}
+
+func (q *Query) parseFilters(values url.Values) error {
+	for key, vs := range values {
+		if reserved[key] {
+			continue
+		}
+
+		if column, operator, ok := q.parseRange(key); ok {
+			q.filters = append(q.filters, filter{column, operator, vs[:1]})
+			continue
+		}
+
+		column, operator, value := parseFilter(key, vs[0])
+
+		if !q.columns[column] {
+			return fmt.Errorf("unknown filter column: %s", column)
+		}
+
+		f := filter{column: column, operator: operator, values: []string{value}}
+
+		if operator == "=" {
+			f.values = vs
+		}
+
+		for _, v := range f.values {
+			if _, err := q.validate(column, v); err != nil {
+				return err
+			}
+		}
+
+		q.filters = append(q.filters, f)
+	}
+
+	return nil
+}
+
+func (q *Query) parseRange(key string) (string, string, bool) {
+	for prefix, column := range q.resource.Ranges {
+		switch key {
+		case prefix + "_before":
+			return column, "<", true
+		case prefix + "_after":
+			return column, ">", true
+		}
+	}
+
+	return "", "", false
+}
+
+// Parse the column, operator and value of a filter parameter. Comparison operators end up in the
+// key: "stargazer_count>=100" is parsed by net/url as key "stargazer_count>" and value "100",
+// and "stargazer_count>100" as key "stargazer_count>100" without a value.
+func parseFilter(key string, value string) (string, string, string) {
+	switch {
+	case strings.HasSuffix(key, ">"):
+		return strings.TrimSuffix(key, ">"), ">=", value
+	case strings.HasSuffix(key, "<"):
+		return strings.TrimSuffix(key, "<"), "<=", value
+	case strings.HasSuffix(key, "!"):
+		return strings.TrimSuffix(key, "!"), "!=", value
+	}
+
+	if i := strings.IndexAny(key, "<>"); i > 0 && value == "" {
+		return key[:i], key[i : i+1], key[i+1:]
+	}
+
+	return key, "=", value
+}
+
+func (q *Query) parseSort(param string) error {
+	if param == "" {
+		return nil
+	}
+
+	for _, field := range strings.Split(param, ",") {
+		o := order{column: strings.TrimSpace(field)}
+
+		if strings.HasPrefix(o.column, "-") {
+			o.column = o.column[1:]
+			o.desc = true
+		}
+
+		if !q.columns[o.column] {
+			return fmt.Errorf("unknown sort column: %s", o.column)
+		}
+
+		q.orders = append(q.orders, o)
+	}
+
+	return nil
+}
+
+func (q *Query) parseFields(param string) error {
+	if param == "" {
+		return nil
+	}
+
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+
+		if !q.columns[field] {
+			return fmt.Errorf("unknown field: %s", field)
+		}
+
+		q.Fields = append(q.Fields, field)
+	}
+
+	return nil
+}
+
+func (q *Query) parsePagination(values url.Values) error {
+	var err error
+
+	q.Limit = DefaultLimit
+
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > MaxLimit {
+			return fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+	}
+
+	if v := values.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil || q.Offset < 0 {
+			return errors.New("offset must be a non-negative integer")
+		}
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		if q.cursor, err = q.decodeCursor(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sort orders of the query, with "id" as the last order, so the order of the rows is total,
+// which the cursors depend on.
+func (q *Query) sortOrders() []order {
+	orders := q.orders[:len(q.orders):len(q.orders)]
+
+	for _, o := range orders {
+		if o.column == "id" {
+			return orders
+		}
+	}
+
+	return append(orders, order{column: "id"})
+}
+
+// Condition, which selects the rows after the cursor:
+// (a > x) OR (a = x AND b > y) OR (a = x AND b = y AND id > z)
+func (q *Query) keyset() (string, []interface{}) {
+	orders := q.sortOrders()
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	for i, o := range orders {
+		var parts []string
+
+		for j := 0; j < i; j++ {
+			parts = append(parts, q.expr(orders[j].column)+" = ?")
+			args = append(args, q.arg(orders[j].column, q.cursor[j]))
+		}
+
+		operator := " > ?"
+		if o.desc {
+			operator = " < ?"
+		}
+
+		parts = append(parts, q.expr(o.column)+operator)
+		args = append(args, q.arg(o.column, q.cursor[i]))
+
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+func (q *Query) encodeCursor(row interface{}) string {
+	var columns []string
+
+	for _, o := range q.sortOrders() {
+		columns = append(columns, o.column)
+	}
+
+	data, _ := json.Marshal(models.Values(row, columns))
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (q *Query) decodeCursor(cursor string) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var values []string
+
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != len(q.sortOrders()) {
+		return nil, errors.New("invalid cursor, or the sort order has changed")
+	}
+
+	for i, o := range q.sortOrders() {
+		if _, err := q.validate(o.column, values[i]); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	return values, nil
+}
+
+// Link to the next page keeps the parameters of the request, and continues from the cursor,
+// or from the next offset, if the request was paginated with an offset.
+func (q *Query) nextLink(u *url.URL, cursor string) string {
+	values := u.Query()
+
+	if values.Has("offset") {
+		values.Set("offset", strconv.Itoa(q.Offset+q.Limit))
+	} else {
+		values.Set("cursor", cursor)
+	}
+
+	next := *u
+	next.RawQuery = values.Encode()
+
+	return next.RequestURI()
+}
+
+// Columns read from the database, when fields are selected. Sort columns are needed for the cursor.
+func (q *Query) selectedColumns() []string {
+	selected := map[string]bool{}
+
+	for _, field := range q.Fields {
+		selected[field] = true
+	}
+
+	for _, o := range q.sortOrders() {
+		selected[o.column] = true
+	}
+
+	var columns []string
+
+	for _, column := range models.Columns(q.resource.Model) {
+		if selected[column] {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// Returns the rows as they are, or only the selected fields of the rows.
+func (q *Query) project(rows interface{}) interface{} {
+	if q.Fields == nil {
+		return rows
+	}
+
+	v := reflect.ValueOf(rows)
+	result := make([]map[string]interface{}, 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		result = append(result, models.Fields(v.Index(i).Addr().Interface(), q.Fields))
+	}
+
+	return result
+}
+
+func (q *Query) isNumeric(column string) bool {
+	if column == "id" {
+		return true
+	}
+
+	for _, c := range q.resource.Numeric {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SQL expression of the column. Integers stored as strings are cast, and empty values are treated as 0.
+func (q *Query) expr(column string) string {
+	if q.isNumeric(column) && column != "id" {
+		return "CAST(COALESCE(NULLIF(" + column + ", ''), '0') AS BIGINT)"
+	}
+
+	return column
+}
+
+// Parses the value of the column. Empty values of the integers stored as strings are 0, the same
+// as in expr, so a cursor of a row, which wasn't enriched yet, can be continued from.
+func (q *Query) validate(column string, value string) (interface{}, error) {
+	if !q.isNumeric(column) {
+		return value, nil
+	}
+
+	if value == "" && column != "id" {
+		return int64(0), nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", column)
+	}
+
+	return n, nil
+}
+
+// Argument of the SQL expression, values are validated while parsing.
+func (q *Query) arg(column string, value string) interface{} {
+	v, _ := q.validate(column, value)
+	return v
+}
+
+func (q *Query) args(column string, values []string) []interface{} {
+	var args []interface{}
+
+	for _, value := range values {
+		args = append(args, q.arg(column, value))
+	}
+
+	return args
+}
+
+func sqlOperator(operator string) string {
+	if operator == "!=" {
+		return "<>"
+	}
+
+	return operator
+}
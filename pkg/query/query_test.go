@@ -0,0 +1,172 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var repositories = Resource{
+	Model:   models.Repository{},
+	Numeric: []string{"stargazer_count", "commit_count"},
+	Ranges:  map[string]string{"created": "creation_date"},
+}
+
+func newDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Repository{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+
+	return c, w
+}
+
+func cursor(values ...string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{"empty", "/", false},
+		{"equality filter", "/?primary_language=Go&primary_language=Rust", false},
+		{"comparison filter", "/?stargazer_count>=100", false},
+		{"range filter", "/?created_before=2020-01-01", false},
+		{"sort and fields", "/?sort=-stargazer_count,repository_name&fields=repository_name", false},
+		{"pagination", "/?limit=10&offset=20", false},
+		{"numeric filter of a string", "/?stargazer_count>=many", true},
+		{"unknown comparison column", "/?stars>=10", true},
+		{"unknown filter column", "/?primary_language=Go&licnse_info=mit", true},
+		{"parameter of the bulk delete", "/?all=true", false},
+		{"unknown sort column", "/?sort=stars", true},
+		{"unknown field", "/?fields=stars", true},
+		{"limit over the maximum", fmt.Sprintf("/?limit=%d", MaxLimit+1), true},
+		{"negative offset", "/?offset=-1", true},
+		{"cursor of an empty numeric value", "/?sort=stargazer_count&cursor=" + cursor("", "42"), false},
+		{"cursor of a numeric string", "/?sort=stargazer_count&cursor=" + cursor("many", "42"), true},
+		{"cursor of an empty id", "/?cursor=" + cursor(""), true},
+		{"cursor of another sort order", "/?sort=stargazer_count&cursor=" + cursor("42"), true},
+		{"malformed cursor", "/?cursor=not-base64!", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newContext(tt.target)
+
+			_, err := Parse(c, repositories)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%s) error = %v, want error %v", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	c, _ := newContext("/?stargazer_count>=100&license_info!=mit&commit_count<10&created_after=2020-01-01")
+
+	q, err := Parse(c, repositories)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, f := range q.filters {
+		got[f.column] = f.operator + f.values[0]
+	}
+
+	want := map[string]string{
+		"stargazer_count": ">=100",
+		"license_info":    "!=mit",
+		"commit_count":    "<10",
+		"creation_date":   ">2020-01-01",
+	}
+
+	for column, filter := range want {
+		if got[column] != filter {
+			t.Errorf("filter of %s = %q, want %q", column, got[column], filter)
+		}
+	}
+}
+
+// Pages through the rows by the links to the next pages, while some of the rows of the sort
+// column are empty, e.g. repositories, which weren't enriched yet.
+func TestListCursorOfEmptyValues(t *testing.T) {
+	db := newDatabase(t)
+
+	stars := []string{"", "5", "", "0", "12", "", "5", "3", ""}
+	for i, s := range stars {
+		db.Create(&models.Repository{RepositoryName: fmt.Sprintf("github.com/o/r%d", i), StargazerCount: s})
+	}
+
+	for _, sort := range []string{"stargazer_count", "-stargazer_count"} {
+		t.Run(sort, func(t *testing.T) {
+			seen := map[int]bool{}
+			target := "/?limit=2&sort=" + sort
+
+			for pages := 0; target != ""; pages++ {
+				if pages > len(stars) {
+					t.Fatal("pagination doesn't end")
+				}
+
+				c, w := newContext(target)
+				List[models.Repository](c, db, repositories)
+
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET %s = %d: %s", target, w.Code, w.Body)
+				}
+
+				var page struct {
+					Data  []models.Repository `json:"data"`
+					Links Links               `json:"links"`
+				}
+
+				if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, r := range page.Data {
+					if seen[r.Id] {
+						t.Errorf("repository %d is on many pages", r.Id)
+					}
+
+					seen[r.Id] = true
+				}
+
+				target = page.Links.Next
+			}
+
+			if len(seen) != len(stars) {
+				t.Errorf("pages have %d repositories, want %d", len(seen), len(stars))
+			}
+		})
+	}
+}
@@ -1,23 +1,62 @@
 package router
 
 import (
+	"context"
+
+	"github.com/haapjari/glass/pkg/config"
 	"github.com/haapjari/glass/pkg/controllers/commit"
+	"github.com/haapjari/glass/pkg/controllers/dataset"
+	"github.com/haapjari/glass/pkg/controllers/failure"
+	"github.com/haapjari/glass/pkg/controllers/job"
 	"github.com/haapjari/glass/pkg/controllers/repository"
 	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/jobs"
+	"github.com/haapjari/glass/pkg/logging"
 	"github.com/haapjari/glass/pkg/metrics/prom"
+	"github.com/haapjari/glass/pkg/openapi"
+	"github.com/haapjari/glass/pkg/plugins"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func SetupRouter() {
-	r := gin.Default()
-	prom := prom.NewProm()
+// SetupRouter connects to the database, and serves the API until the context is cancelled.
+func SetupRouter(ctx context.Context, c *config.Config) error {
+	db, err := database.SetupDatabase(c.Database)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- NewRouter(ctx, db, c).Run()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
 
-	db := database.SetupDatabase()
+// NewRouter registers the routes of the API. Every route needs an operation in the OpenAPI
+// specification, which the tests check with openapi.Validate. Background work of the router, the
+// metrics of the dataset and the job queue, stops, when the context is cancelled.
+func NewRouter(ctx context.Context, db *gorm.DB, c *config.Config) *gin.Engine {
+	r := gin.New()
+	// Recovery runs last, so the requests, which panic, are logged and measured as 500.
+	r.Use(logging.Gin(), prom.Middleware(), gin.Recovery())
+	prom.NewDataset(db, plugins.Languages).Watch(ctx, c.Metrics.DatasetInterval)
+	prom := prom.NewProm()
+	queue := jobs.NewQueue(ctx, db, c)
 
-	r.Use(func(c *gin.Context) {
-		c.Set("db", db)
-		c.Next()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("db", db)
+		ctx.Set("config", c)
+		ctx.Set("jobs", queue)
+		ctx.Next()
 	})
 
 	r.GET("/api/glass/v1/commit", commit.GetCommits)
@@ -25,19 +64,40 @@ func SetupRouter() {
 	r.GET("/api/glass/v1/commit/:id", commit.GetCommitById)
 	r.DELETE("/api/glass/v1/commit/:id", commit.DeleteCommitById)
 	r.PATCH("/api/glass/v1/commit/:id", commit.UpdateCommitById)
+	r.GET("/api/glass/v1/commit/csv", commit.GenerateCsv)
+	r.POST("/api/glass/v1/commit/bulk", commit.BulkCreateCommits)
+	r.PATCH("/api/glass/v1/commit/bulk", commit.BulkUpdateCommits)
+	r.DELETE("/api/glass/v1/commit/bulk", commit.BulkDeleteCommits)
 
 	r.GET("/api/glass/v1/repository", repository.GetRepositories)
 	r.POST("/api/glass/v1/repository", repository.CreateRepository)
 	r.GET("/api/glass/v1/repository/:id", repository.GetRepositoryById)
 	r.DELETE("/api/glass/v1/repository/:id", repository.DeleteRepositoryById)
 	r.PATCH("/api/glass/v1/repository/:id", repository.UpdateRepositoryById)
+	r.POST("/api/glass/v1/repository/bulk", repository.BulkCreateRepositories)
+	r.PATCH("/api/glass/v1/repository/bulk", repository.BulkUpdateRepositories)
+	r.DELETE("/api/glass/v1/repository/bulk", repository.BulkDeleteRepositories)
 
 	r.GET("/api/glass/v1/repository/fetch", repository.FetchRepositories)
+	r.GET("/api/glass/v1/repository/csv", repository.GenerateCsv)
+
+	r.GET("/api/glass/v1/job", job.GetJobs)
+	r.POST("/api/glass/v1/job", job.CreateJob)
+	r.GET("/api/glass/v1/job/:id", job.GetJobById)
+	r.GET("/api/glass/v1/job/:id/logs", job.GetJobLogs)
+
+	r.GET("/api/glass/v1/failures", failure.GetFailures)
+	r.GET("/api/glass/v1/failures/:id", failure.GetFailureById)
+	r.POST("/api/glass/v1/failures/:id/retry", failure.RetryFailureById)
+	r.POST("/api/glass/v1/failures/retry", failure.RetryFailures)
+
+	r.GET("/api/glass/v1/export", dataset.Export)
+	r.POST("/api/glass/v1/import", dataset.Import)
 
-	// TODO
-	//	r.GET("/api/glass/v1/repository/csv", repository.GenerateCsv)
+	r.GET("/api/glass/v1/openapi.json", openapi.Handler)
+	r.GET("/api/glass/v1/docs", openapi.UIHandler)
 
 	r.GET("/api/glass/v1/metrics", prom.Handler)
 
-	r.Run()
+	return r
 }
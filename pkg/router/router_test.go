@@ -0,0 +1,124 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/haapjari/glass/pkg/config"
+	"github.com/haapjari/glass/pkg/database"
+	"github.com/haapjari/glass/pkg/export"
+	"github.com/haapjari/glass/pkg/models"
+	"github.com/haapjari/glass/pkg/openapi"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Every route of the router is in the OpenAPI specification, and the other way round.
+func TestRoutesAreSpecified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := NewRouter(ctx, db, &config.Config{})
+
+	if err := openapi.Validate(r.Routes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	source, err := gorm.Open(sqlite.Open("file:"+t.Name()+"source?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(source); err != nil {
+		t.Fatal(err)
+	}
+
+	source.Create(&models.Repository{RepositoryName: "github.com/o/a", RepositoryUrl: "github.com/o/a"})
+
+	bundle, err := export.NewBundle(source, export.FormatJsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer bundle.Remove()
+
+	var archive bytes.Buffer
+
+	if err := bundle.Archive(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	var form bytes.Buffer
+
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", bundle.FileName())
+	fw.Write(archive.Bytes())
+	mw.Close()
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         []byte
+		code         int
+		repositories int64
+	}{
+		{"multipart form", mw.FormDataContentType(), form.Bytes(), http.StatusOK, 1},
+		{"body of curl --data-binary", "application/x-www-form-urlencoded", archive.Bytes(), http.StatusOK, 1},
+		{"gzip body", "application/gzip", archive.Bytes(), http.StatusOK, 1},
+		{"multipart form without the file", mw.FormDataContentType(), []byte("--" + mw.Boundary() + "--\r\n"), http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := database.Migrate(db); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, openapi.BasePath+"/import", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			NewRouter(ctx, db, &config.Config{}).ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+
+			var count int64
+			db.Model(&models.Repository{}).Count(&count)
+
+			if count != tt.repositories {
+				t.Errorf("repositories = %d, want %d", count, tt.repositories)
+			}
+		})
+	}
+}
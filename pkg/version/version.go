@@ -0,0 +1,10 @@
+package version
+
+// Version of Glass. Overridden at build time with:
+// go build -ldflags "-X github.com/haapjari/glass/pkg/version.Version=v0.0.2"
+var Version = "v0.0.1"
+
+// Version of the Quality Measure formula (see "Quality Measure" in README.md). Increment it, when
+// the factors, thresholds or weights of the formula change, so the datasets calculated with
+// different formulas can be told apart.
+const QualityMeasureVersion = "1"